@@ -0,0 +1,61 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterBackupScheduleSpec defines a recurring cluster-wide backup.
+type ClusterBackupScheduleSpec struct {
+	ScheduleSpec `json:",inline"`
+	// Template is the spec used for every ClusterBackup created by this schedule.
+	Template ClusterBackupSpec `json:"template"`
+}
+
+// ClusterBackupScheduleStatus defines status for cluster backup schedules.
+type ClusterBackupScheduleStatus struct {
+	ScheduleStatus `json:",inline"`
+}
+
+// ClusterBackupSchedule is the Schema for the clusterbackupschedules API.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=cbs
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=".spec.schedule"
+// +kubebuilder:printcolumn:name="Last",type=date,JSONPath=".status.lastScheduleTime"
+// +kubebuilder:printcolumn:name="Next",type=date,JSONPath=".status.nextScheduleTime"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=".metadata.creationTimestamp"
+type ClusterBackupSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterBackupScheduleSpec   `json:"spec,omitempty"`
+	Status ClusterBackupScheduleStatus `json:"status,omitempty"`
+}
+
+// ClusterBackupScheduleList contains a list of ClusterBackupSchedule.
+// +kubebuilder:object:root=true
+type ClusterBackupScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterBackupSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterBackupSchedule{}, &ClusterBackupScheduleList{})
+}
@@ -41,8 +41,43 @@ const (
 	ExportFormatJSON ExportFormat = "json"
 )
 
+// BackupDeletionPolicy defines what happens to stored data when a backup is deleted.
+// +kubebuilder:validation:Enum=Delete;Retain
+// +kubebuilder:default=Delete
+type BackupDeletionPolicy string
+
+const (
+	BackupDeletionPolicyDelete BackupDeletionPolicy = "Delete"
+	BackupDeletionPolicyRetain BackupDeletionPolicy = "Retain"
+)
+
+// BackupErrorPolicy defines how a backup treats per-resource export errors.
+// +kubebuilder:validation:Enum=Continue;Fail
+// +kubebuilder:default=Continue
+type BackupErrorPolicy string
+
+const (
+	// BackupErrorPolicyContinue records the error and finishes the backup as PartiallyFailed.
+	BackupErrorPolicyContinue BackupErrorPolicy = "Continue"
+	// BackupErrorPolicyFail fails the backup on the first error.
+	BackupErrorPolicyFail BackupErrorPolicy = "Fail"
+)
+
+// BackupCleanupFinalizer holds Backup and ClusterBackup deletion until the artifact and volume snapshots are cleaned up.
+const BackupCleanupFinalizer = "backup.example.com/artifact-cleanup"
+
+const (
+	// BackupSyncedLabel marks Backup and ClusterBackup resources recreated from an artifact found in storage. They are
+	// read-only records: no worker runs for them, and deleting them leaves the artifact in place.
+	BackupSyncedLabel = "backup.example.com/synced"
+	// BackupSourceClusterAnnotation records the CLUSTER_ID of the cluster that wrote a synced backup's artifact.
+	BackupSourceClusterAnnotation = "backup.example.com/source-cluster"
+	// BackupArtifactAnnotation records the artifact location a synced backup was created from.
+	BackupArtifactAnnotation = "backup.example.com/artifact-location"
+)
+
 // BackupPhase indicates the lifecycle state of a backup.
-// +kubebuilder:validation:Enum=Pending;Running;Completed;Failed
+// +kubebuilder:validation:Enum=Pending;Running;Completed;PartiallyFailed;Failed
 // +kubebuilder:default=Pending
 type BackupPhase string
 
@@ -50,11 +85,13 @@ const (
 	BackupPhasePending   BackupPhase = "Pending"
 	BackupPhaseRunning   BackupPhase = "Running"
 	BackupPhaseCompleted BackupPhase = "Completed"
-	BackupPhaseFailed    BackupPhase = "Failed"
+	// BackupPhasePartiallyFailed means the artifact was stored but some resources could not be exported.
+	BackupPhasePartiallyFailed BackupPhase = "PartiallyFailed"
+	BackupPhaseFailed          BackupPhase = "Failed"
 )
 
 // RestorePhase indicates the lifecycle state of a restore.
-// +kubebuilder:validation:Enum=Pending;Running;Completed;Failed
+// +kubebuilder:validation:Enum=Pending;Running;Completed;PartiallyFailed;Failed
 // +kubebuilder:default=Pending
 type RestorePhase string
 
@@ -62,16 +99,33 @@ const (
 	RestorePhasePending   RestorePhase = "Pending"
 	RestorePhaseRunning   RestorePhase = "Running"
 	RestorePhaseCompleted RestorePhase = "Completed"
-	RestorePhaseFailed    RestorePhase = "Failed"
+	// RestorePhasePartiallyFailed means the restore went through the whole artifact but some objects failed.
+	RestorePhasePartiallyFailed RestorePhase = "PartiallyFailed"
+	RestorePhaseFailed          RestorePhase = "Failed"
+)
+
+// RestoreErrorPolicy defines how a restore treats objects that fail to apply.
+// +kubebuilder:validation:Enum=FailFast;Continue
+// +kubebuilder:default=FailFast
+type RestoreErrorPolicy string
+
+const (
+	// RestoreErrorPolicyFailFast fails the restore on the first object that fails to apply.
+	RestoreErrorPolicyFailFast RestoreErrorPolicy = "FailFast"
+	// RestoreErrorPolicyContinue records the failure, restores the remaining objects and finishes the restore as
+	// PartiallyFailed.
+	RestoreErrorPolicyContinue RestoreErrorPolicy = "Continue"
 )
 
 // StorageLocationType describes where backup artifacts are stored.
-// +kubebuilder:validation:Enum=s3;nfs
+// +kubebuilder:validation:Enum=s3;nfs;azure;gcs
 type StorageLocationType string
 
 const (
-	StorageLocationS3  StorageLocationType = "s3"
-	StorageLocationNFS StorageLocationType = "nfs"
+	StorageLocationS3    StorageLocationType = "s3"
+	StorageLocationNFS   StorageLocationType = "nfs"
+	StorageLocationAzure StorageLocationType = "azure"
+	StorageLocationGCS   StorageLocationType = "gcs"
 )
 
 // StorageLocationPhase indicates the readiness of a storage location.
@@ -96,6 +150,27 @@ const (
 	RestoreOverwriteSkip    RestoreOverwritePolicy = "Skip"
 )
 
+// ScheduleConcurrencyPolicy defines how a schedule treats overlapping runs.
+// +kubebuilder:validation:Enum=Forbid;Allow;Replace
+// +kubebuilder:default=Forbid
+type ScheduleConcurrencyPolicy string
+
+const (
+	ScheduleConcurrencyForbid  ScheduleConcurrencyPolicy = "Forbid"
+	ScheduleConcurrencyAllow   ScheduleConcurrencyPolicy = "Allow"
+	ScheduleConcurrencyReplace ScheduleConcurrencyPolicy = "Replace"
+)
+
+// ScheduleMissedRunPolicy defines how a schedule treats runs missed while the operator was unavailable.
+// +kubebuilder:validation:Enum=Skip;CatchUp
+// +kubebuilder:default=Skip
+type ScheduleMissedRunPolicy string
+
+const (
+	ScheduleMissedRunSkip    ScheduleMissedRunPolicy = "Skip"
+	ScheduleMissedRunCatchUp ScheduleMissedRunPolicy = "CatchUp"
+)
+
 // RemoteAuthMethod defines how the operator authenticates to a remote cluster.
 // +kubebuilder:validation:Enum=ServiceAccountToken;Kubeconfig
 // +kubebuilder:default=ServiceAccountToken
@@ -138,6 +213,37 @@ type ResourceSelector struct {
 	AnnotationSelector map[string]string `json:"annotationSelector,omitempty"`
 }
 
+// EncryptionSpec configures client-side encryption of backup artifacts.
+type EncryptionSpec struct {
+	// SecretRef references a Secret whose data keys are key IDs and whose values are 32-byte AES-256 keys, raw or
+	// base64-encoded. Keep retired keys in the Secret so artifacts encrypted with them stay readable.
+	SecretRef corev1.SecretReference `json:"secretRef"`
+	// KeyID selects the key that encrypts new artifacts. It may be omitted when the Secret holds a single key.
+	KeyID string `json:"keyID,omitempty"`
+}
+
+// ArtifactEncryption records how an artifact was encrypted.
+type ArtifactEncryption struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyID"`
+}
+
+// SigningSpec configures detached signatures of artifact manifests.
+type SigningSpec struct {
+	// SecretRef references a Secret holding the PEM-encoded ed25519 or ECDSA private key that signs new artifacts,
+	// under the data key private.pem. Leave it unset on locations that are only restored from.
+	SecretRef *corev1.SecretReference `json:"secretRef,omitempty"`
+	// TrustedPublicKeys are PEM-encoded public keys accepted when verifying artifacts. When any are set, restores
+	// refuse artifacts that are not signed by one of them.
+	TrustedPublicKeys []string `json:"trustedPublicKeys,omitempty"`
+}
+
+// ArtifactSignature records the key that signed an artifact's manifest.
+type ArtifactSignature struct {
+	Algorithm      string `json:"algorithm"`
+	KeyFingerprint string `json:"keyFingerprint"`
+}
+
 // NamespaceSelector controls which namespaces are in scope for a cluster backup.
 type NamespaceSelector struct {
 	Included []string `json:"included,omitempty"`
@@ -162,28 +268,151 @@ type BackupSpec struct {
 	TTL *metav1.Duration `json:"ttl,omitempty"`
 	// RetainUntil keeps backup artifacts until the given time.
 	RetainUntil *metav1.Time `json:"retainUntil,omitempty"`
+	// DeletionPolicy controls whether the artifact and volume snapshots are removed when the backup is deleted.
+	DeletionPolicy BackupDeletionPolicy `json:"deletionPolicy,omitempty"`
+	// ErrorPolicy controls whether errors listing or snapshotting individual resources fail the backup.
+	ErrorPolicy BackupErrorPolicy `json:"errorPolicy,omitempty"`
+	// Encryption overrides the storage location's artifact encryption settings.
+	Encryption *EncryptionSpec `json:"encryption,omitempty"`
 }
 
+// Condition types written to BackupStatus.Conditions as each backup stage finishes.
+const (
+	BackupConditionStorageResolved   = "StorageResolved"
+	BackupConditionResourcesExported = "ResourcesExported"
+	BackupConditionSnapshotsCreated  = "SnapshotsCreated"
+	BackupConditionSnapshotsReady    = "SnapshotsReady"
+	BackupConditionArtifactUploaded  = "ArtifactUploaded"
+	BackupConditionCompleted         = "Completed"
+	// BackupConditionArtifactVerified reports the outcome of the last verify-worker run against the artifact.
+	BackupConditionArtifactVerified = "ArtifactVerified"
+	// BackupConditionArtifactsDeleted reports the outcome of cleanup while the backup is being deleted.
+	BackupConditionArtifactsDeleted = "ArtifactsDeleted"
+)
+
+// Condition types written to BackupStorageLocationStatus.Conditions.
+const (
+	// StorageLocationConditionAvailable is written by each probe.
+	StorageLocationConditionAvailable = "Available"
+	// StorageLocationConditionCatalogSynced is written by each catalog sync.
+	StorageLocationConditionCatalogSynced = "CatalogSynced"
+)
+
+// Condition types written to RestoreStatus.Conditions as each restore stage finishes.
+const (
+	RestoreConditionArtifactVerified   = "ArtifactVerified"
+	RestoreConditionSignatureVerified  = "SignatureVerified"
+	RestoreConditionArtifactDownloaded = "ArtifactDownloaded"
+	RestoreConditionResourcesApplied   = "ResourcesApplied"
+	RestoreConditionWorkloadsReady     = "WorkloadsReady"
+	// RestoreConditionPlanned replaces ResourcesApplied and WorkloadsReady on dry-run restores.
+	RestoreConditionPlanned = "Planned"
+)
+
+// Reasons shared by backup, restore and storage location conditions.
+const (
+	ConditionReasonSucceeded = "Succeeded"
+	ConditionReasonFailed    = "Failed"
+	// ConditionReasonSkipped marks a stage that had nothing to do.
+	ConditionReasonSkipped = "Skipped"
+	// ConditionReasonNotAwaited marks a stage the worker does not wait for in Async execution mode.
+	ConditionReasonNotAwaited = "NotAwaited"
+	// ConditionReasonTimedOut marks a Sync-mode stage that did not finish within its deadline.
+	ConditionReasonTimedOut = "TimedOut"
+	// ConditionReasonPartiallyFailed marks a stage that finished with per-resource errors.
+	ConditionReasonPartiallyFailed = "PartiallyFailed"
+	// ConditionReasonInvalidConfiguration marks a storage location whose settings are incomplete.
+	ConditionReasonInvalidConfiguration = "InvalidConfiguration"
+	// ConditionReasonVolumeReplacing marks an NFS location whose PersistentVolume is recreated for a changed export.
+	ConditionReasonVolumeReplacing = "VolumeReplacing"
+	// ConditionReasonStorageUnavailable marks a backup held back because its storage location is unavailable.
+	ConditionReasonStorageUnavailable = "StorageUnavailable"
+	// ConditionReasonSynced marks a backup recreated from an artifact found in storage.
+	ConditionReasonSynced = "Synced"
+)
+
 // BackupStatus defines common backup status fields.
 type BackupStatus struct {
 	Phase              BackupPhase        `json:"phase,omitempty"`
 	Conditions         []metav1.Condition `json:"conditions,omitempty"`
 	StartedAt          *metav1.Time       `json:"startedAt,omitempty"`
 	CompletedAt        *metav1.Time       `json:"completedAt,omitempty"`
+	ExpiresAt          *metav1.Time       `json:"expiresAt,omitempty"`
 	ArtifactLocation   string             `json:"artifactLocation,omitempty"`
 	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
 	Message            string             `json:"message,omitempty"`
+	// Warnings and Errors count the entries recorded in the artifact's errors.json.
+	Warnings int32 `json:"warnings,omitempty"`
+	Errors   int32 `json:"errors,omitempty"`
+	// Encryption is set when the artifact is encrypted.
+	Encryption *ArtifactEncryption `json:"encryption,omitempty"`
+	// ArtifactDigest is the SHA-256 digest of the stored artifact, as "sha256:<hex>". It is also stored next to the
+	// artifact with a .sha256 suffix.
+	ArtifactDigest string `json:"artifactDigest,omitempty"`
+	// Signature is set when the artifact's manifest is signed. The signature is stored next to the artifact with a
+	// .sig suffix.
+	Signature *ArtifactSignature `json:"signature,omitempty"`
+}
+
+// ScheduleSpec defines common schedule inputs.
+type ScheduleSpec struct {
+	// Schedule is a standard five-field cron expression.
+	Schedule string `json:"schedule"`
+	// TimeZone is an IANA time zone name used to evaluate Schedule. Defaults to UTC.
+	TimeZone *string `json:"timeZone,omitempty"`
+	// Suspend stops new runs from being created when true.
+	Suspend *bool `json:"suspend,omitempty"`
+	// ConcurrencyPolicy controls what happens when a run is due while a previous one is still active.
+	ConcurrencyPolicy ScheduleConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	// MissedRunPolicy controls whether a run missed by more than StartingDeadlineSeconds is skipped or caught up.
+	MissedRunPolicy ScheduleMissedRunPolicy `json:"missedRunPolicy,omitempty"`
+	// StartingDeadlineSeconds bounds how late a run may start before it counts as missed. Defaults to 300.
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+}
+
+// ScheduleStatus defines common schedule status fields.
+type ScheduleStatus struct {
+	Conditions           []metav1.Condition `json:"conditions,omitempty"`
+	LastScheduleTime     *metav1.Time       `json:"lastScheduleTime,omitempty"`
+	NextScheduleTime     *metav1.Time       `json:"nextScheduleTime,omitempty"`
+	LastSuccessfulBackup string             `json:"lastSuccessfulBackup,omitempty"`
+	LastSuccessfulTime   *metav1.Time       `json:"lastSuccessfulTime,omitempty"`
+	Active               []string           `json:"active,omitempty"`
+	MissedRuns           int32              `json:"missedRuns,omitempty"`
+	ObservedGeneration   int64              `json:"observedGeneration,omitempty"`
+	Message              string             `json:"message,omitempty"`
 }
 
 // RestoreSourceRef identifies the backup to restore from.
-// Kind must be Backup or ClusterBackup.
+// Kind must be Backup, ClusterBackup or Artifact. For Artifact, Name and Namespace are those of the backup that
+// wrote the artifact (no namespace for a ClusterBackup), unless Artifact.Path gives the artifact directly.
 type RestoreSourceRef struct {
-	// +kubebuilder:validation:Enum=Backup;ClusterBackup
+	// +kubebuilder:validation:Enum=Backup;ClusterBackup;Artifact
 	Kind      string `json:"kind"`
-	Name      string `json:"name"`
+	Name      string `json:"name,omitempty"`
 	Namespace string `json:"namespace,omitempty"`
+	// Artifact locates the artifact in storage when Kind is Artifact, so no Backup or ClusterBackup has to exist.
+	Artifact *ArtifactSource `json:"artifact,omitempty"`
+}
+
+// ArtifactSource locates an artifact in a storage location, either by its path or by the cluster that wrote it
+// together with sourceRef name and namespace.
+type ArtifactSource struct {
+	// StorageRef names the BackupStorageLocation; defaults to the default location.
+	StorageRef corev1.LocalObjectReference `json:"storageRef,omitempty"`
+	// Path is the artifact's key within the location, e.g.
+	// <cluster-id>/backup/<namespace>/<name>/<timestamp>/backup.tar.gz, or its full location as recorded in
+	// status.artifactLocation.
+	Path string `json:"path,omitempty"`
+	// ClusterID is the CLUSTER_ID of the cluster that wrote the artifact; defaults to this cluster's.
+	ClusterID string `json:"clusterID,omitempty"`
+	// Timestamp selects one run of the backup, e.g. 20240102T030405Z; defaults to the latest.
+	Timestamp string `json:"timestamp,omitempty"`
 }
 
+// RestoreSourceArtifact is the sourceRef kind that restores from an artifact in storage.
+const RestoreSourceArtifact = "Artifact"
+
 // RestoreSpec defines common restore inputs.
 type RestoreSpec struct {
 	SourceRef        RestoreSourceRef             `json:"sourceRef"`
@@ -192,16 +421,56 @@ type RestoreSpec struct {
 	OverwritePolicy  RestoreOverwritePolicy       `json:"overwritePolicy,omitempty"`
 	ExecutionMode    ExecutionMode                `json:"executionMode,omitempty"`
 	Timeout          *metav1.Duration             `json:"timeout,omitempty"`
+	// IgnoreIntegrityErrors restores an artifact whose digest or manifest does not match instead of refusing it.
+	IgnoreIntegrityErrors bool `json:"ignoreIntegrityErrors,omitempty"`
+	// DryRun only plans the restore: every object is sent to the target cluster as a server-side dry run, and
+	// the resulting plan, with a diff against live state for each object, is stored next to the artifact.
+	DryRun bool `json:"dryRun,omitempty"`
+	// ErrorPolicy controls whether an object that fails to apply fails the restore.
+	ErrorPolicy RestoreErrorPolicy `json:"errorPolicy,omitempty"`
+	// ResourceModifierRef names a ConfigMap whose resourceModifiers.yaml key holds rules that patch matching
+	// objects before they are restored. A Restore reads it from its own namespace, a ClusterRestore from the
+	// operator namespace.
+	ResourceModifierRef *corev1.LocalObjectReference `json:"resourceModifierRef,omitempty"`
+}
+
+// RestorePlanSummary counts the planned action for each object of a dry-run restore.
+type RestorePlanSummary struct {
+	// Location is where the full plan was stored: next to the artifact, as
+	// <artifact>.plan.<kind>.<namespace>.<name>.json. It is empty when the storage location is read-only.
+	Location string `json:"location,omitempty"`
+	Create   int32  `json:"create,omitempty"`
+	Update   int32  `json:"update,omitempty"`
+	Skip     int32  `json:"skip,omitempty"`
+	Conflict int32  `json:"conflict,omitempty"`
+}
+
+// RestoreResourceCounts summarizes what happened to each restored object.
+type RestoreResourceCounts struct {
+	Created  int32 `json:"created,omitempty"`
+	Updated  int32 `json:"updated,omitempty"`
+	Skipped  int32 `json:"skipped,omitempty"`
+	Replaced int32 `json:"replaced,omitempty"`
+	Failed   int32 `json:"failed,omitempty"`
+	// Unmapped counts objects whose kind the target cluster does not serve.
+	Unmapped int32 `json:"unmapped,omitempty"`
 }
 
 // RestoreStatus defines common restore status fields.
 type RestoreStatus struct {
-	Phase              RestorePhase       `json:"phase,omitempty"`
-	Conditions         []metav1.Condition `json:"conditions,omitempty"`
-	StartedAt          *metav1.Time       `json:"startedAt,omitempty"`
-	CompletedAt        *metav1.Time       `json:"completedAt,omitempty"`
-	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
-	Message            string             `json:"message,omitempty"`
+	Phase       RestorePhase           `json:"phase,omitempty"`
+	Conditions  []metav1.Condition     `json:"conditions,omitempty"`
+	StartedAt   *metav1.Time           `json:"startedAt,omitempty"`
+	CompletedAt *metav1.Time           `json:"completedAt,omitempty"`
+	Resources   *RestoreResourceCounts `json:"resources,omitempty"`
+	// ResultsLocation is where the result for each object was stored: next to the artifact, as
+	// <artifact>.restore-results.<kind>.<namespace>.<name>.json rather than a fixed restore-results.json, so
+	// restores of the same artifact do not overwrite each other's results. It is empty when the storage location
+	// is read-only or the results could not be stored.
+	ResultsLocation    string              `json:"resultsLocation,omitempty"`
+	Plan               *RestorePlanSummary `json:"plan,omitempty"`
+	ObservedGeneration int64               `json:"observedGeneration,omitempty"`
+	Message            string              `json:"message,omitempty"`
 }
 
 // S3LocationSpec configures an S3-compatible storage backend.
@@ -228,12 +497,48 @@ type NFSLocationSpec struct {
 	CredentialsSecretRef *corev1.SecretReference `json:"credentialsSecretRef,omitempty"`
 }
 
+// AzureLocationSpec configures an Azure Blob Storage backend.
+type AzureLocationSpec struct {
+	// Account is the storage account name.
+	Account   string `json:"account,omitempty"`
+	Container string `json:"container,omitempty"`
+	Prefix    string `json:"prefix,omitempty"`
+	// Endpoint overrides the blob service URL, https://<account>.blob.core.windows.net by default. For Azurite it
+	// includes the account, e.g. http://azurite:10000/devstoreaccount1.
+	Endpoint string `json:"endpoint,omitempty"`
+	// SecretRef references a Secret with either an accountKey (shared key) or a sasToken.
+	SecretRef corev1.SecretReference `json:"secretRef,omitempty"`
+}
+
+// GCSLocationSpec configures a Google Cloud Storage backend.
+type GCSLocationSpec struct {
+	Bucket string `json:"bucket,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	// Endpoint overrides the JSON API URL, e.g. http://fake-gcs-server:4443/storage/v1/ for testing.
+	Endpoint string `json:"endpoint,omitempty"`
+	// SecretRef references a Secret with a service account key under key.json. Without it, the worker uses
+	// Application Default Credentials such as GKE Workload Identity.
+	SecretRef corev1.SecretReference `json:"secretRef,omitempty"`
+}
+
 // BackupStorageLocationSpec defines where backup artifacts are stored.
 type BackupStorageLocationSpec struct {
 	Type    StorageLocationType `json:"type"`
 	S3      *S3LocationSpec     `json:"s3,omitempty"`
 	NFS     *NFSLocationSpec    `json:"nfs,omitempty"`
+	Azure   *AzureLocationSpec  `json:"azure,omitempty"`
+	GCS     *GCSLocationSpec    `json:"gcs,omitempty"`
 	Default bool                `json:"default,omitempty"`
+	// Encryption encrypts artifacts written to this location.
+	Encryption *EncryptionSpec `json:"encryption,omitempty"`
+	// Signing signs artifacts written to this location and verifies artifacts restored from it.
+	Signing *SigningSpec `json:"signing,omitempty"`
+	// ValidationInterval is how often the location is probed by writing, reading back and deleting a sentinel
+	// object. Defaults to 5m; 0 probes only when the spec changes.
+	ValidationInterval *metav1.Duration `json:"validationInterval,omitempty"`
+	// SyncInterval is how often artifacts in the location without a Backup or ClusterBackup, including those
+	// written by other clusters, are recreated as synced resources. Defaults to 10m; 0 disables catalog sync.
+	SyncInterval *metav1.Duration `json:"syncInterval,omitempty"`
 }
 
 // BackupStorageLocationStatus reports storage validation results.
@@ -241,7 +546,12 @@ type BackupStorageLocationStatus struct {
 	Phase         StorageLocationPhase `json:"phase,omitempty"`
 	Conditions    []metav1.Condition   `json:"conditions,omitempty"`
 	LastValidated *metav1.Time         `json:"lastValidated,omitempty"`
-	Message       string               `json:"message,omitempty"`
+	// ValidationLatency is how long the last successful probe took.
+	ValidationLatency *metav1.Duration `json:"validationLatency,omitempty"`
+	// LastSynced is when the catalog of artifacts in the location was last synced into the cluster.
+	LastSynced         *metav1.Time `json:"lastSynced,omitempty"`
+	ObservedGeneration int64        `json:"observedGeneration,omitempty"`
+	Message            string       `json:"message,omitempty"`
 }
 
 // RemoteClusterAuth describes how to authenticate to a remote cluster.
@@ -24,6 +24,60 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+func (in *ArtifactEncryption) DeepCopyInto(out *ArtifactEncryption) {
+	*out = *in
+}
+
+func (in *ArtifactEncryption) DeepCopy() *ArtifactEncryption {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactEncryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ArtifactSignature) DeepCopyInto(out *ArtifactSignature) {
+	*out = *in
+}
+
+func (in *ArtifactSignature) DeepCopy() *ArtifactSignature {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactSignature)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ArtifactSource) DeepCopyInto(out *ArtifactSource) {
+	*out = *in
+	out.StorageRef = in.StorageRef
+}
+
+func (in *ArtifactSource) DeepCopy() *ArtifactSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *AzureLocationSpec) DeepCopyInto(out *AzureLocationSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+func (in *AzureLocationSpec) DeepCopy() *AzureLocationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureLocationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 func (in *Backup) DeepCopyInto(out *Backup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
@@ -76,6 +130,87 @@ func (in *BackupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+func (in *BackupSchedule) DeepCopyInto(out *BackupSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+func (in *BackupSchedule) DeepCopy() *BackupSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *BackupSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *BackupScheduleList) DeepCopyInto(out *BackupScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]BackupSchedule, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *BackupScheduleList) DeepCopy() *BackupScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *BackupScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *BackupScheduleSpec) DeepCopyInto(out *BackupScheduleSpec) {
+	*out = *in
+	in.ScheduleSpec.DeepCopyInto(&out.ScheduleSpec)
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+func (in *BackupScheduleSpec) DeepCopy() *BackupScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *BackupScheduleStatus) DeepCopyInto(out *BackupScheduleStatus) {
+	*out = *in
+	in.ScheduleStatus.DeepCopyInto(&out.ScheduleStatus)
+}
+
+func (in *BackupScheduleStatus) DeepCopy() *BackupScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
 	*out = *in
 	if in.StorageRef != nil {
@@ -106,6 +241,10 @@ func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
 		out.RetainUntil = new(metav1.Time)
 		*out.RetainUntil = *in.RetainUntil
 	}
+	if in.Encryption != nil {
+		out.Encryption = new(EncryptionSpec)
+		*out.Encryption = *in.Encryption
+	}
 }
 
 func (in *BackupSpec) DeepCopy() *BackupSpec {
@@ -131,6 +270,18 @@ func (in *BackupStatus) DeepCopyInto(out *BackupStatus) {
 		out.CompletedAt = new(metav1.Time)
 		*out.CompletedAt = *in.CompletedAt
 	}
+	if in.ExpiresAt != nil {
+		out.ExpiresAt = new(metav1.Time)
+		*out.ExpiresAt = *in.ExpiresAt
+	}
+	if in.Encryption != nil {
+		out.Encryption = new(ArtifactEncryption)
+		*out.Encryption = *in.Encryption
+	}
+	if in.Signature != nil {
+		out.Signature = new(ArtifactSignature)
+		*out.Signature = *in.Signature
+	}
 }
 
 func (in *BackupStatus) DeepCopy() *BackupStatus {
@@ -204,6 +355,30 @@ func (in *BackupStorageLocationSpec) DeepCopyInto(out *BackupStorageLocationSpec
 		out.NFS = new(NFSLocationSpec)
 		in.NFS.DeepCopyInto(out.NFS)
 	}
+	if in.Azure != nil {
+		out.Azure = new(AzureLocationSpec)
+		*out.Azure = *in.Azure
+	}
+	if in.GCS != nil {
+		out.GCS = new(GCSLocationSpec)
+		*out.GCS = *in.GCS
+	}
+	if in.Encryption != nil {
+		out.Encryption = new(EncryptionSpec)
+		*out.Encryption = *in.Encryption
+	}
+	if in.Signing != nil {
+		out.Signing = new(SigningSpec)
+		in.Signing.DeepCopyInto(out.Signing)
+	}
+	if in.ValidationInterval != nil {
+		out.ValidationInterval = new(metav1.Duration)
+		*out.ValidationInterval = *in.ValidationInterval
+	}
+	if in.SyncInterval != nil {
+		out.SyncInterval = new(metav1.Duration)
+		*out.SyncInterval = *in.SyncInterval
+	}
 }
 
 func (in *BackupStorageLocationSpec) DeepCopy() *BackupStorageLocationSpec {
@@ -225,6 +400,14 @@ func (in *BackupStorageLocationStatus) DeepCopyInto(out *BackupStorageLocationSt
 		out.LastValidated = new(metav1.Time)
 		*out.LastValidated = *in.LastValidated
 	}
+	if in.ValidationLatency != nil {
+		out.ValidationLatency = new(metav1.Duration)
+		*out.ValidationLatency = *in.ValidationLatency
+	}
+	if in.LastSynced != nil {
+		out.LastSynced = new(metav1.Time)
+		*out.LastSynced = *in.LastSynced
+	}
 }
 
 func (in *BackupStorageLocationStatus) DeepCopy() *BackupStorageLocationStatus {
@@ -288,6 +471,87 @@ func (in *ClusterBackupList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+func (in *ClusterBackupSchedule) DeepCopyInto(out *ClusterBackupSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+func (in *ClusterBackupSchedule) DeepCopy() *ClusterBackupSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBackupSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ClusterBackupSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *ClusterBackupScheduleList) DeepCopyInto(out *ClusterBackupScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]ClusterBackupSchedule, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *ClusterBackupScheduleList) DeepCopy() *ClusterBackupScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBackupScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ClusterBackupScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *ClusterBackupScheduleSpec) DeepCopyInto(out *ClusterBackupScheduleSpec) {
+	*out = *in
+	in.ScheduleSpec.DeepCopyInto(&out.ScheduleSpec)
+	in.Template.DeepCopyInto(&out.Template)
+}
+
+func (in *ClusterBackupScheduleSpec) DeepCopy() *ClusterBackupScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBackupScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ClusterBackupScheduleStatus) DeepCopyInto(out *ClusterBackupScheduleStatus) {
+	*out = *in
+	in.ScheduleStatus.DeepCopyInto(&out.ScheduleStatus)
+}
+
+func (in *ClusterBackupScheduleStatus) DeepCopy() *ClusterBackupScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterBackupScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 func (in *ClusterBackupSpec) DeepCopyInto(out *ClusterBackupSpec) {
 	*out = *in
 	in.BackupSpec.DeepCopyInto(&out.BackupSpec)
@@ -404,6 +668,20 @@ func (in *ClusterRestoreStatus) DeepCopy() *ClusterRestoreStatus {
 	return out
 }
 
+func (in *EncryptionSpec) DeepCopyInto(out *EncryptionSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+func (in *EncryptionSpec) DeepCopy() *EncryptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EncryptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 func (in *ExportSpec) DeepCopyInto(out *ExportSpec) {
 	*out = *in
 	if in.Enabled != nil {
@@ -421,6 +699,20 @@ func (in *ExportSpec) DeepCopy() *ExportSpec {
 	return out
 }
 
+func (in *GCSLocationSpec) DeepCopyInto(out *GCSLocationSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+func (in *GCSLocationSpec) DeepCopy() *GCSLocationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GCSLocationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 func (in *NamespaceSelector) DeepCopyInto(out *NamespaceSelector) {
 	*out = *in
 	if in.Included != nil {
@@ -654,8 +946,52 @@ func (in *RestoreList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+func (in *RestorePlanSummary) DeepCopyInto(out *RestorePlanSummary) {
+	*out = *in
+}
+
+func (in *RestorePlanSummary) DeepCopy() *RestorePlanSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(RestorePlanSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *RestoreResourceCounts) DeepCopyInto(out *RestoreResourceCounts) {
+	*out = *in
+}
+
+func (in *RestoreResourceCounts) DeepCopy() *RestoreResourceCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreResourceCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *RestoreSourceRef) DeepCopyInto(out *RestoreSourceRef) {
+	*out = *in
+	if in.Artifact != nil {
+		out.Artifact = new(ArtifactSource)
+		*out.Artifact = *in.Artifact
+	}
+}
+
+func (in *RestoreSourceRef) DeepCopy() *RestoreSourceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreSourceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 	*out = *in
+	in.SourceRef.DeepCopyInto(&out.SourceRef)
 	if in.TargetClusterRef != nil {
 		out.TargetClusterRef = new(corev1.LocalObjectReference)
 		*out.TargetClusterRef = *in.TargetClusterRef
@@ -670,6 +1006,10 @@ func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 		out.Timeout = new(metav1.Duration)
 		*out.Timeout = *in.Timeout
 	}
+	if in.ResourceModifierRef != nil {
+		out.ResourceModifierRef = new(corev1.LocalObjectReference)
+		*out.ResourceModifierRef = *in.ResourceModifierRef
+	}
 }
 
 func (in *RestoreSpec) DeepCopy() *RestoreSpec {
@@ -695,6 +1035,14 @@ func (in *RestoreStatus) DeepCopyInto(out *RestoreStatus) {
 		out.CompletedAt = new(metav1.Time)
 		*out.CompletedAt = *in.CompletedAt
 	}
+	if in.Resources != nil {
+		out.Resources = new(RestoreResourceCounts)
+		*out.Resources = *in.Resources
+	}
+	if in.Plan != nil {
+		out.Plan = new(RestorePlanSummary)
+		*out.Plan = *in.Plan
+	}
 }
 
 func (in *RestoreStatus) DeepCopy() *RestoreStatus {
@@ -723,6 +1071,85 @@ func (in *S3LocationSpec) DeepCopy() *S3LocationSpec {
 	return out
 }
 
+func (in *ScheduleSpec) DeepCopyInto(out *ScheduleSpec) {
+	*out = *in
+	if in.TimeZone != nil {
+		out.TimeZone = new(string)
+		*out.TimeZone = *in.TimeZone
+	}
+	if in.Suspend != nil {
+		out.Suspend = new(bool)
+		*out.Suspend = *in.Suspend
+	}
+	if in.StartingDeadlineSeconds != nil {
+		out.StartingDeadlineSeconds = new(int64)
+		*out.StartingDeadlineSeconds = *in.StartingDeadlineSeconds
+	}
+}
+
+func (in *ScheduleSpec) DeepCopy() *ScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *ScheduleStatus) DeepCopyInto(out *ScheduleStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(in.Conditions))
+		copy(out.Conditions, in.Conditions)
+	}
+	if in.LastScheduleTime != nil {
+		out.LastScheduleTime = new(metav1.Time)
+		*out.LastScheduleTime = *in.LastScheduleTime
+	}
+	if in.NextScheduleTime != nil {
+		out.NextScheduleTime = new(metav1.Time)
+		*out.NextScheduleTime = *in.NextScheduleTime
+	}
+	if in.LastSuccessfulTime != nil {
+		out.LastSuccessfulTime = new(metav1.Time)
+		*out.LastSuccessfulTime = *in.LastSuccessfulTime
+	}
+	if in.Active != nil {
+		out.Active = make([]string, len(in.Active))
+		copy(out.Active, in.Active)
+	}
+}
+
+func (in *ScheduleStatus) DeepCopy() *ScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *SigningSpec) DeepCopyInto(out *SigningSpec) {
+	*out = *in
+	if in.SecretRef != nil {
+		out.SecretRef = new(corev1.SecretReference)
+		*out.SecretRef = *in.SecretRef
+	}
+	if in.TrustedPublicKeys != nil {
+		out.TrustedPublicKeys = make([]string, len(in.TrustedPublicKeys))
+		copy(out.TrustedPublicKeys, in.TrustedPublicKeys)
+	}
+}
+
+func (in *SigningSpec) DeepCopy() *SigningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SigningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 func (in *SnapshotSpec) DeepCopyInto(out *SnapshotSpec) {
 	*out = *in
 	if in.Enabled != nil {
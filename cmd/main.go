@@ -24,6 +24,7 @@ import (
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
 	"example.com/backup-operator/controllers"
+	webhookv1alpha1 "example.com/backup-operator/internal/webhook/v1alpha1"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -63,16 +64,19 @@ func main() {
 	var metricsCertPath, metricsCertName, metricsCertKey string
 	var webhookCertPath, webhookCertName, webhookCertKey string
 	var enableLeaderElection bool
+	var gcDryRun bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
-	flag.StringVar(&mode, "mode", "manager", "Run mode: manager, backup-worker, restore-worker.")
-	flag.StringVar(&workerKind, "worker-kind", "", "Worker resource kind (Backup, ClusterBackup, Restore, ClusterRestore).")
+	flag.StringVar(&mode, "mode", "manager", "Run mode: manager, backup-worker, restore-worker, gc-worker, cleanup-worker, verify-worker, probe-worker, catalog-worker.")
+	flag.StringVar(&workerKind, "worker-kind", "", "Worker resource kind (Backup, ClusterBackup, Restore, ClusterRestore, BackupStorageLocation).")
 	flag.StringVar(&workerName, "worker-name", "", "Worker resource name.")
 	flag.StringVar(&workerNamespace, "worker-namespace", "", "Worker resource namespace (empty for cluster-scoped).")
+	flag.BoolVar(&gcDryRun, "gc-dry-run", false,
+		"If set, expired backups are only logged and their artifacts are kept.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
@@ -106,6 +110,7 @@ func main() {
 			namespace: workerNamespace,
 		}); err != nil {
 			setupLog.Error(err, "worker execution failed")
+			writeTerminationMessage(err)
 			os.Exit(1)
 		}
 		return
@@ -228,6 +233,10 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "BackupStorageLocation")
 		os.Exit(1)
 	}
+	if err = (&controllers.BackupCatalogReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BackupCatalog")
+		os.Exit(1)
+	}
 	if err = (&controllers.BackupReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Backup")
 		os.Exit(1)
@@ -244,11 +253,54 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterRestore")
 		os.Exit(1)
 	}
+	if err = (&controllers.BackupScheduleReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BackupSchedule")
+		os.Exit(1)
+	}
+	if err = (&controllers.ClusterBackupScheduleReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterBackupSchedule")
+		os.Exit(1)
+	}
+	if err = (&controllers.BackupGCReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), DryRun: gcDryRun}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BackupGC")
+		os.Exit(1)
+	}
+	if err = (&controllers.ClusterBackupGCReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme(), DryRun: gcDryRun}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterBackupGC")
+		os.Exit(1)
+	}
 	if err = (&controllers.RemoteClusterReconciler{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "RemoteCluster")
 		os.Exit(1)
 	}
 
+	// Webhooks need serving certificates; set ENABLE_WEBHOOKS=false when running the manager locally.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupBackupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Backup")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupClusterBackupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterBackup")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupRestoreWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Restore")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupClusterRestoreWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterRestore")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupBackupStorageLocationWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BackupStorageLocation")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupRemoteClusterWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "RemoteCluster")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if metricsCertWatcher != nil {
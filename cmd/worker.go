@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
@@ -14,6 +16,41 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	terminationMessagePath  = "/dev/termination-log"
+	terminationMessageLimit = 4096
+
+	defaultWorkerQPS       = 50
+	defaultWorkerBurst     = 100
+	defaultListConcurrency = 8
+)
+
+const (
+	// snapshotsReadyTimeout and workloadsAvailableTimeout bound the Sync-mode waits, so a snapshot or workload that
+	// never becomes ready fails its stage instead of holding the worker until the Job deadline.
+	snapshotsReadyTimeout     = 30 * time.Minute
+	workloadsAvailableTimeout = 10 * time.Minute
+)
+
+// stageTimeoutError reports a Sync-mode wait that ran past its deadline.
+type stageTimeoutError struct {
+	waitingFor string
+	timeout    time.Duration
+}
+
+func (e *stageTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s", e.timeout, e.waitingFor)
+}
+
+// failureReason is the condition reason for a stage that failed with err.
+func failureReason(err error) string {
+	var timeout *stageTimeoutError
+	if errors.As(err, &timeout) {
+		return backupv1alpha1.ConditionReasonTimedOut
+	}
+	return backupv1alpha1.ConditionReasonFailed
+}
+
 type workerConfig struct {
 	mode      string
 	kind      string
@@ -34,7 +71,7 @@ func runWorker(ctx context.Context, cfg workerConfig) error {
 		return err
 	}
 
-	restCfg := ctrl.GetConfigOrDie()
+	restCfg := restConfigForWorker(ctrl.GetConfigOrDie())
 	c, err := client.New(restCfg, client.Options{Scheme: scheme})
 	if err != nil {
 		return err
@@ -45,11 +82,30 @@ func runWorker(ctx context.Context, cfg workerConfig) error {
 		return runBackupWorker(ctx, c, restCfg, cfg)
 	case "restore-worker":
 		return runRestoreWorker(ctx, c, restCfg, cfg)
+	case "gc-worker":
+		return runGCWorker(ctx, c, restCfg, cfg)
+	case "cleanup-worker":
+		return runCleanupWorker(ctx, c, restCfg, cfg)
+	case "verify-worker":
+		return runVerifyWorker(ctx, c, cfg)
+	case "probe-worker":
+		return runProbeWorker(ctx, c, cfg)
+	case "catalog-worker":
+		return runCatalogWorker(ctx, c, cfg)
 	default:
 		return fmt.Errorf("unknown worker mode %q", cfg.mode)
 	}
 }
 
+// writeTerminationMessage surfaces a worker failure through the pod status, where the controller reads it.
+func writeTerminationMessage(err error) {
+	message := err.Error()
+	if len(message) > terminationMessageLimit {
+		message = message[:terminationMessageLimit]
+	}
+	_ = os.WriteFile(terminationMessagePath, []byte(message), 0o644)
+}
+
 func workerContext() (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), 2*time.Hour)
 }
@@ -77,6 +133,31 @@ func operatorImage() string {
 	return getEnvOrDefault("OPERATOR_IMAGE", "controller:latest")
 }
 
+// restConfigForWorker raises the client-side rate limits, which default to 5 QPS and would throttle exports of
+// large clusters. WORKER_KUBE_API_QPS and WORKER_KUBE_API_BURST override them.
 func restConfigForWorker(cfg *rest.Config) *rest.Config {
+	cfg.QPS = float32(envFloat("WORKER_KUBE_API_QPS", defaultWorkerQPS))
+	cfg.Burst = envInt("WORKER_KUBE_API_BURST", defaultWorkerBurst)
 	return cfg
 }
+
+// listConcurrency is how many List calls a backup worker keeps in flight, set with WORKER_LIST_CONCURRENCY.
+func listConcurrency() int {
+	return envInt("WORKER_LIST_CONCURRENCY", defaultListConcurrency)
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -5,43 +5,61 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io"
+	"path"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
 	"example.com/backup-operator/internal/resolve"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	sigsyaml "sigs.k8s.io/yaml"
 )
 
 const (
 	artifactFileName = "backup.tar.gz"
+	// metadataEntryName is the first entry of every archive. It describes the backup that wrote it.
+	metadataEntryName = "metadata.json"
+	// resourcesEntryDir holds one archive entry per exported object. Older artifacts carry a single
+	// resources.yaml instead.
+	resourcesEntryDir = "resources"
+
+	labelSnapshotBackupName = "backup.example.com/backup-name"
+	labelSnapshotBackupKind = "backup.example.com/backup-kind"
 )
 
+var volumeSnapshotGVR = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+
 type backupObject struct {
 	kind                    string
 	name                    string
 	namespace               string
+	generation              int64
 	spec                    backupv1alpha1.BackupSpec
 	namespaces              *backupv1alpha1.NamespaceSelector
 	includeClusterResources bool
 	status                  backupv1alpha1.BackupStatus
 	updateStatus            func(status backupv1alpha1.BackupStatus) error
+	deleteObject            func() error
+	removeFinalizer         func() error
 }
 
 func runBackupWorker(ctx context.Context, c client.Client, restCfg *rest.Config, cfg workerConfig) error {
@@ -56,57 +74,228 @@ func runBackupWorker(ctx context.Context, c client.Client, restCfg *rest.Config,
 	}
 	storage, err := resolve.StorageLocation(ctx, c, storageName)
 	if err != nil {
-		return backup.updateStatus(failedBackupStatus(backup.status, backup, err.Error()))
+		return backup.fail(backupv1alpha1.BackupConditionStorageResolved, err)
+	}
+	if err := backup.setCondition(backupv1alpha1.BackupConditionStorageResolved, metav1.ConditionTrue,
+		backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("using BackupStorageLocation %s", storage.Name)); err != nil {
+		return err
 	}
 
 	now := time.Now().UTC()
 	timestamp := now.Format("20060102T150405Z")
 
-	workDir, err := os.MkdirTemp("", "backup-worker-")
+	metadataBytes, err := buildBackupMetadata(backup, storage, timestamp)
 	if err != nil {
-		return err
+		return backup.fail(backupv1alpha1.BackupConditionArtifactUploaded, err)
 	}
-	defer os.RemoveAll(workDir)
 
-	resourcesBytes, err := exportResources(ctx, restCfg, backup)
-	if err != nil {
-		return backup.updateStatus(failedBackupStatus(backup.status, backup, err.Error()))
+	fileName := artifactFileName
+	var keyID string
+	var key []byte
+	if enc := encryptionSpecFor(backup.spec, storage); enc != nil {
+		keyID, key, err = activeEncryptionKey(ctx, c, enc)
+		if err != nil {
+			return backup.fail(backupv1alpha1.BackupConditionArtifactUploaded, err)
+		}
+		fileName += encryptedFileSuffix
 	}
-
-	snapshotsBytes, err := exportSnapshots(ctx, restCfg, backup)
+	signer, err := loadSigningKey(ctx, c, storage)
 	if err != nil {
-		return backup.updateStatus(failedBackupStatus(backup.status, backup, err.Error()))
+		return backup.fail(backupv1alpha1.BackupConditionArtifactUploaded, err)
 	}
 
-	metadataBytes, err := buildBackupMetadata(backup, storage, timestamp)
+	// The archive is streamed straight into storage, so every stage that contributes to it runs while the upload
+	// is open. failedStage tracks which one to blame if the stream breaks.
+	failedStage := backupv1alpha1.BackupConditionArtifactUploaded
+	issues := newExportIssues(backup.spec.ErrorPolicy)
+	syncMode := backup.spec.ExecutionMode == backupv1alpha1.ExecutionModeSync
+	var manifest []byte
+	writeArchive := func(w io.Writer) error {
+		archive := newArtifactWriter(w)
+		if err := archive.add(metadataEntryName, metadataBytes); err != nil {
+			return err
+		}
+
+		failedStage = backupv1alpha1.BackupConditionResourcesExported
+		exported, err := exportResources(ctx, restCfg, backup, archive, issues)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !exportEnabled(backup.spec):
+			err = backup.setCondition(backupv1alpha1.BackupConditionResourcesExported, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonSkipped, "resource export disabled")
+		case issues.count(issueLevelError) > 0:
+			err = backup.setCondition(backupv1alpha1.BackupConditionResourcesExported, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonPartiallyFailed, fmt.Sprintf("exported %d resources with %d errors",
+					exported, issues.count(issueLevelError)))
+		default:
+			err = backup.setCondition(backupv1alpha1.BackupConditionResourcesExported, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("exported %d resources", exported))
+		}
+		if err != nil {
+			return err
+		}
+
+		failedStage = backupv1alpha1.BackupConditionSnapshotsCreated
+		exportErrors := issues.count(issueLevelError)
+		snapshotsBytes, snapshots, err := exportSnapshots(ctx, restCfg, backup, issues)
+		if err != nil {
+			return err
+		}
+		switch snapshotErrors := issues.count(issueLevelError) - exportErrors; {
+		case snapshotErrors > 0:
+			err = backup.setCondition(backupv1alpha1.BackupConditionSnapshotsCreated, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonPartiallyFailed, fmt.Sprintf("created %d volume snapshots with %d errors",
+					len(snapshots), snapshotErrors))
+		case len(snapshots) == 0:
+			err = backup.setCondition(backupv1alpha1.BackupConditionSnapshotsCreated, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonSkipped, "no volume snapshots requested")
+		default:
+			err = backup.setCondition(backupv1alpha1.BackupConditionSnapshotsCreated, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("created %d volume snapshots", len(snapshots)))
+		}
+		if err != nil {
+			return err
+		}
+
+		// In Sync mode Completed must mean the backup is usable, so wait for the snapshots to be cut.
+		failedStage = backupv1alpha1.BackupConditionSnapshotsReady
+		switch {
+		case len(snapshots) == 0:
+			err = backup.setCondition(backupv1alpha1.BackupConditionSnapshotsReady, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonSkipped, "no volume snapshots requested")
+		case syncMode:
+			if err := waitForSnapshotsReady(ctx, restCfg, snapshots); err != nil {
+				return err
+			}
+			err = backup.setCondition(backupv1alpha1.BackupConditionSnapshotsReady, metav1.ConditionTrue,
+				backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("%d volume snapshots ready", len(snapshots)))
+		default:
+			err = backup.setCondition(backupv1alpha1.BackupConditionSnapshotsReady, metav1.ConditionUnknown,
+				backupv1alpha1.ConditionReasonNotAwaited, "snapshot readiness is only awaited in Sync execution mode")
+		}
+		if err != nil {
+			return err
+		}
+
+		failedStage = backupv1alpha1.BackupConditionArtifactUploaded
+		if err := archive.add("snapshots.yaml", snapshotsBytes); err != nil {
+			return err
+		}
+		issuesBytes, err := issues.marshal()
+		if err != nil {
+			return err
+		}
+		if err := archive.add("errors.json", issuesBytes); err != nil {
+			return err
+		}
+		if err := archive.Close(); err != nil {
+			return err
+		}
+		manifest = archive.manifestJSON
+		return nil
+	}
+	digest := sha256.New()
+	location, size, err := storeArtifact(ctx, c, storage, backup, timestamp, fileName, func(w io.Writer) error {
+		return sealArtifact(io.MultiWriter(w, digest), keyID, key, writeArchive)
+	})
+	backup.status.Warnings = issues.count(issueLevelWarning)
+	backup.status.Errors = issues.count(issueLevelError)
 	if err != nil {
-		return backup.updateStatus(failedBackupStatus(backup.status, backup, err.Error()))
+		return backup.fail(failedStage, err)
 	}
+	// Recorded before the steps that follow, so a backup that fails in one of them still refers to the artifact it
+	// uploaded and the finalizer and garbage collection can remove it.
+	backup.status.ArtifactLocation = location
 
-	artifactPath := filepath.Join(workDir, artifactFileName)
-	files := map[string][]byte{
-		"metadata.json":  metadataBytes,
-		"resources.yaml": resourcesBytes,
-		"snapshots.yaml": snapshotsBytes,
+	artifactDigest := formatDigest(digest.Sum(nil))
+	if syncMode {
+		if err := verifyArtifact(ctx, c, storage, location, size, artifactDigest); err != nil {
+			return backup.fail(backupv1alpha1.BackupConditionArtifactUploaded, err)
+		}
 	}
-	if err := writeTarGz(artifactPath, files); err != nil {
-		return backup.updateStatus(failedBackupStatus(backup.status, backup, err.Error()))
+	if err := storeArtifactSidecar(ctx, c, storage, location, digestFileSuffix, digestFileContent(artifactDigest, location)); err != nil {
+		return backup.fail(backupv1alpha1.BackupConditionArtifactUploaded, fmt.Errorf("store artifact digest: %w", err))
 	}
-
-	location, err := storeArtifact(ctx, c, storage, artifactPath, backup, timestamp)
-	if err != nil {
-		return backup.updateStatus(failedBackupStatus(backup.status, backup, err.Error()))
+	if signer != nil {
+		signature, err := signArtifact(ctx, c, storage, location, signer, manifest)
+		if err != nil {
+			return backup.fail(backupv1alpha1.BackupConditionArtifactUploaded, fmt.Errorf("sign artifact: %w", err))
+		}
+		backup.status.Signature = signature
+	}
+	backup.status.ArtifactDigest = artifactDigest
+	if key != nil {
+		backup.status.Encryption = &backupv1alpha1.ArtifactEncryption{Algorithm: encryptionAlgorithm, KeyID: keyID}
+	}
+	if err := backup.setCondition(backupv1alpha1.BackupConditionArtifactUploaded, metav1.ConditionTrue,
+		backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("artifact stored at %s", location)); err != nil {
+		return err
 	}
 
 	completed := backup.status
 	completed.Phase = backupv1alpha1.BackupPhaseCompleted
 	completed.CompletedAt = &metav1.Time{Time: now}
-	completed.ArtifactLocation = location
 	completed.Message = "backup completed"
-	completed.ObservedGeneration = backup.status.ObservedGeneration
+	reason := backupv1alpha1.ConditionReasonSucceeded
+	if completed.Errors > 0 {
+		// The artifact is usable, but some resources are missing from it; errors.json lists them.
+		completed.Phase = backupv1alpha1.BackupPhasePartiallyFailed
+		completed.Message = fmt.Sprintf("backup partially failed: %d errors, %d warnings recorded in errors.json",
+			completed.Errors, completed.Warnings)
+		reason = backupv1alpha1.ConditionReasonPartiallyFailed
+	}
+	// The entry is written last, once status refers to the artifact, so catalog sync never mistakes an artifact
+	// that is still being recorded for one without a backup.
+	entry, err := buildCatalogEntry(backup, timestamp, completed)
+	if err == nil {
+		err = storeArtifactSidecar(ctx, c, storage, location, metadataFileSuffix, entry)
+	}
+	if err != nil {
+		return backup.fail(backupv1alpha1.BackupConditionArtifactUploaded, fmt.Errorf("store catalog entry: %w", err))
+	}
+	meta.SetStatusCondition(&completed.Conditions, metav1.Condition{
+		Type:               backupv1alpha1.BackupConditionCompleted,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            completed.Message,
+		ObservedGeneration: backup.generation,
+	})
 	return backup.updateStatus(completed)
 }
 
+// setCondition records the outcome of a backup stage and persists it right away.
+func (b *backupObject) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) error {
+	meta.SetStatusCondition(&b.status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: b.generation,
+	})
+	return b.updateStatus(b.status)
+}
+
+// fail marks the stage that failed and the backup as a whole as Failed.
+func (b *backupObject) fail(conditionType string, err error) error {
+	failed := failedBackupStatus(b.status, b, err.Error())
+	for _, condition := range []string{conditionType, backupv1alpha1.BackupConditionCompleted} {
+		meta.SetStatusCondition(&failed.Conditions, metav1.Condition{
+			Type:               condition,
+			Status:             metav1.ConditionFalse,
+			Reason:             failureReason(err),
+			Message:            err.Error(),
+			ObservedGeneration: b.generation,
+		})
+	}
+	return b.updateStatus(failed)
+}
+
+func exportEnabled(spec backupv1alpha1.BackupSpec) bool {
+	return spec.Export == nil || spec.Export.Enabled == nil || *spec.Export.Enabled
+}
+
 func loadBackupObject(ctx context.Context, c client.Client, cfg workerConfig) (*backupObject, error) {
 	switch cfg.kind {
 	case "Backup":
@@ -115,15 +304,25 @@ func loadBackupObject(ctx context.Context, c client.Client, cfg workerConfig) (*
 			return nil, err
 		}
 		return &backupObject{
-			kind:      "Backup",
-			name:      backup.Name,
-			namespace: backup.Namespace,
-			spec:      backup.Spec,
-			status:    backup.Status,
+			kind:       "Backup",
+			name:       backup.Name,
+			namespace:  backup.Namespace,
+			generation: backup.Generation,
+			spec:       backup.Spec,
+			status:     backup.Status,
 			updateStatus: func(status backupv1alpha1.BackupStatus) error {
 				backup.Status = status
 				return c.Status().Update(ctx, &backup)
 			},
+			deleteObject: func() error {
+				return c.Delete(ctx, &backup)
+			},
+			removeFinalizer: func() error {
+				if !controllerutil.RemoveFinalizer(&backup, backupv1alpha1.BackupCleanupFinalizer) {
+					return nil
+				}
+				return c.Update(ctx, &backup)
+			},
 		}, nil
 	case "ClusterBackup":
 		var backup backupv1alpha1.ClusterBackup
@@ -138,6 +337,7 @@ func loadBackupObject(ctx context.Context, c client.Client, cfg workerConfig) (*
 			kind:                    "ClusterBackup",
 			name:                    backup.Name,
 			namespace:               "",
+			generation:              backup.Generation,
 			spec:                    backup.Spec.BackupSpec,
 			namespaces:              backup.Spec.Namespaces,
 			includeClusterResources: includeCluster,
@@ -146,6 +346,15 @@ func loadBackupObject(ctx context.Context, c client.Client, cfg workerConfig) (*
 				backup.Status.BackupStatus = status
 				return c.Status().Update(ctx, &backup)
 			},
+			deleteObject: func() error {
+				return c.Delete(ctx, &backup)
+			},
+			removeFinalizer: func() error {
+				if !controllerutil.RemoveFinalizer(&backup, backupv1alpha1.BackupCleanupFinalizer) {
+					return nil
+				}
+				return c.Update(ctx, &backup)
+			},
 		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported backup kind %q", cfg.kind)
@@ -157,10 +366,11 @@ func failedBackupStatus(current backupv1alpha1.BackupStatus, backup *backupObjec
 	current.Phase = backupv1alpha1.BackupPhaseFailed
 	current.Message = message
 	current.CompletedAt = &now
-	current.ObservedGeneration = current.ObservedGeneration
 	return current
 }
 
+// buildBackupMetadata describes the backup with the fields of a catalogEntry, so catalog sync can recreate the
+// backup resource from an unencrypted archive whose catalog entry is missing.
 func buildBackupMetadata(backup *backupObject, storage *backupv1alpha1.BackupStorageLocation, timestamp string) ([]byte, error) {
 	metadata := map[string]any{
 		"kind":        backup.kind,
@@ -169,22 +379,43 @@ func buildBackupMetadata(backup *backupObject, storage *backupv1alpha1.BackupSto
 		"clusterID":   clusterID(),
 		"timestamp":   timestamp,
 		"storageType": string(storage.Spec.Type),
+		"spec":        backup.spec,
+	}
+	if backup.kind == "ClusterBackup" {
+		metadata["namespaces"] = backup.namespaces
+		metadata["includeClusterResources"] = backup.includeClusterResources
 	}
 	return json.MarshalIndent(metadata, "", "  ")
 }
 
-func exportResources(ctx context.Context, restCfg *rest.Config, backup *backupObject) ([]byte, error) {
-	if backup.spec.Export != nil && backup.spec.Export.Enabled != nil && !*backup.spec.Export.Enabled {
-		return []byte(""), nil
+const (
+	// exportPageSize is how many objects a single List call returns while exporting.
+	exportPageSize = 500
+	// clusterWideListRatio is the share of namespaces above which namespaced resources are listed cluster-wide.
+	clusterWideListRatio = 0.5
+)
+
+// exportTarget is one listable resource type selected for export.
+type exportTarget struct {
+	resource   schema.GroupVersionResource
+	kind       string
+	namespaced bool
+}
+
+// exportResources lists the selected resources page by page and writes each object to the archive as its own
+// entry, in the order a restore has to apply them. It returns the number of exported objects.
+func exportResources(ctx context.Context, restCfg *rest.Config, backup *backupObject, archive *artifactWriter, issues *exportIssues) (int, error) {
+	if !exportEnabled(backup.spec) {
+		return 0, nil
 	}
 
 	dyn, err := dynamic.NewForConfig(restCfg)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	disco, err := discovery.NewDiscoveryClientForConfig(restCfg)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	labelSelector := ""
 	annotationSelector := map[string]string{}
@@ -195,7 +426,7 @@ func exportResources(ctx context.Context, restCfg *rest.Config, backup *backupOb
 		if backup.spec.Resources.LabelSelector != nil {
 			selector, err := metav1.LabelSelectorAsSelector(backup.spec.Resources.LabelSelector)
 			if err != nil {
-				return nil, err
+				return 0, err
 			}
 			labelSelector = selector.String()
 		}
@@ -217,18 +448,31 @@ func exportResources(ctx context.Context, restCfg *rest.Config, backup *backupOb
 
 	namespaces, err := resolveNamespaces(ctx, restCfg, backup)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	resources, err := disco.ServerPreferredResources()
 	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
-		return nil, err
+		return 0, err
+	}
+	if failed, ok := err.(*discovery.ErrGroupDiscoveryFailed); ok {
+		// Typically a broken aggregated API; everything it serves is missing from the backup.
+		groups := make([]schema.GroupVersion, 0, len(failed.Groups))
+		for gv := range failed.Groups {
+			groups = append(groups, gv)
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i].String() < groups[j].String() })
+		for _, gv := range groups {
+			if err := issues.record(gv.WithResource(""), "", failed.Groups[gv]); err != nil {
+				return 0, err
+			}
+		}
 	}
 
-	selected := []*unstructured.Unstructured{}
 	includeSet := sets.NewString(normalizeResourceNames(includedResources)...)
 	excludeSet := sets.NewString(normalizeResourceNames(excludedResources)...)
 
+	var targets []exportTarget
 	for _, list := range resources {
 		gv, err := schema.ParseGroupVersion(list.GroupVersion)
 		if err != nil {
@@ -252,70 +496,224 @@ func exportResources(ctx context.Context, restCfg *rest.Config, backup *backupOb
 			if shouldSkipKind(res.Kind) {
 				continue
 			}
-
-			gvr := schema.GroupVersionResource{Group: gv.Group, Version: gv.Version, Resource: res.Name}
-			if res.Namespaced {
-				for _, ns := range namespaces {
-					listOpts := metav1.ListOptions{LabelSelector: labelSelector}
-					items, err := dyn.Resource(gvr).Namespace(ns).List(ctx, listOpts)
-					if err != nil {
-						continue
-					}
-					for i := range items.Items {
-						item := items.Items[i]
-						if !matchAnnotations(item.GetAnnotations(), annotationSelector) {
-							continue
-						}
-						sanitizeObject(&item)
-						selected = append(selected, &item)
-					}
-				}
-			} else if includeCluster {
-				listOpts := metav1.ListOptions{LabelSelector: labelSelector}
-				items, err := dyn.Resource(gvr).List(ctx, listOpts)
-				if err != nil {
-					continue
-				}
-				for i := range items.Items {
-					item := items.Items[i]
-					if !matchAnnotations(item.GetAnnotations(), annotationSelector) {
-						continue
-					}
-					sanitizeObject(&item)
-					selected = append(selected, &item)
-				}
+			if !res.Namespaced && !includeCluster {
+				continue
 			}
+
+			targets = append(targets, exportTarget{
+				resource:   schema.GroupVersionResource{Group: gv.Group, Version: gv.Version, Resource: res.Name},
+				kind:       res.Kind,
+				namespaced: res.Namespaced,
+			})
 		}
 	}
 
-	sort.SliceStable(selected, func(i, j int) bool {
-		return resourcePriority(selected[i]) < resourcePriority(selected[j])
+	// Objects are never sorted in memory, so the archive order comes from visiting resource types by priority.
+	sort.SliceStable(targets, func(i, j int) bool {
+		return kindPriority(targets[i].kind) < kindPriority(targets[j].kind)
 	})
 
-	var buffer bytes.Buffer
-	for _, obj := range selected {
+	clusterWide, err := listClusterWide(ctx, restCfg, backup, namespaces)
+	if err != nil {
+		return 0, err
+	}
+	selectedNamespaces := sets.New(namespaces...)
+
+	var tasks []*listTask
+	for _, target := range targets {
+		switch {
+		case !target.namespaced:
+			tasks = append(tasks, newListTask(dyn, target.resource, "", nil))
+		case clusterWide:
+			tasks = append(tasks, newListTask(dyn, target.resource, "", selectedNamespaces))
+		default:
+			for _, ns := range namespaces {
+				tasks = append(tasks, newListTask(dyn, target.resource, ns, nil))
+			}
+		}
+	}
+
+	exported := 0
+	err = listInOrder(ctx, tasks, labelSelector, func(item *unstructured.Unstructured) error {
+		if !matchAnnotations(item.GetAnnotations(), annotationSelector) {
+			return nil
+		}
+		sanitizeObject(item)
 		var out []byte
+		var err error
 		if exportFormat == backupv1alpha1.ExportFormatJSON {
-			out, err = json.Marshal(obj.Object)
+			out, err = json.Marshal(item.Object)
 		} else {
-			out, err = sigsyaml.Marshal(obj.Object)
+			out, err = sigsyaml.Marshal(item.Object)
 		}
 		if err != nil {
-			return nil, err
+			return err
 		}
 		if len(out) == 0 {
+			return nil
+		}
+		if err := archive.add(resourceEntryName(item, exportFormat), out); err != nil {
+			return err
+		}
+		exported++
+		return nil
+	}, func(task *listTask) error {
+		return issues.record(task.gvr, task.namespace, task.err)
+	})
+	return exported, err
+}
+
+// listTask pages through one resource type, either in a single namespace or across the cluster.
+type listTask struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	resource  dynamic.ResourceInterface
+	// namespaces filters the items of a cluster-wide list down to the selected namespaces.
+	namespaces sets.Set[string]
+	pages      chan []unstructured.Unstructured
+	// err is only safe to read once pages is closed.
+	err error
+}
+
+func newListTask(dyn dynamic.Interface, gvr schema.GroupVersionResource, namespace string, namespaces sets.Set[string]) *listTask {
+	task := &listTask{gvr: gvr, namespace: namespace, resource: dyn.Resource(gvr), namespaces: namespaces,
+		pages: make(chan []unstructured.Unstructured, 1)}
+	if namespace != "" {
+		task.resource = dyn.Resource(gvr).Namespace(namespace)
+	}
+	return task
+}
+
+func (t *listTask) run(ctx context.Context, labelSelector string) {
+	defer close(t.pages)
+	opts := metav1.ListOptions{LabelSelector: labelSelector, Limit: exportPageSize}
+	for {
+		page, err := t.resource.List(ctx, opts)
+		if token := expiredListContinue(err); token != "" && opts.Continue != "" {
+			// While this task waited for the consumer, etcd compacted the revision its list was reading. The
+			// 410 carries a token that picks up after the last item at the current revision: the rest of the
+			// list is no longer one consistent snapshot, but no object is skipped or listed twice.
+			opts.Continue = token
 			continue
 		}
-		buffer.WriteString("---\n")
-		buffer.Write(out)
+		if err != nil {
+			t.err = err
+			return
+		}
+		select {
+		case t.pages <- page.Items:
+		case <-ctx.Done():
+			t.err = ctx.Err()
+			return
+		}
+		if page.GetContinue() == "" {
+			return
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
+
+// expiredListContinue returns the continue token of a 410 ResourceExpired response to a paged List, or "".
+func expiredListContinue(err error) string {
+	if !errors.IsResourceExpired(err) {
+		return ""
+	}
+	status, ok := err.(errors.APIStatus)
+	if !ok {
+		return ""
+	}
+	return status.Status().ListMeta.Continue
+}
+
+// listInOrder runs tasks on a bounded pool of workers and hands every listed object to visit in task order, so the
+// archive stays deterministic however the List calls interleave. A task blocks once it has a page waiting, which
+// keeps memory bounded by the pool size rather than the number of objects. Tasks whose List fails are handed to
+// failed, which stops the export by returning an error.
+func listInOrder(ctx context.Context, tasks []*listTask, labelSelector string, visit func(*unstructured.Unstructured) error,
+	failed func(*listTask) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+	defer workers.Wait()
+	defer cancel()
+
+	queue := make(chan *listTask)
+	go func() {
+		defer close(queue)
+		for _, task := range tasks {
+			select {
+			case queue <- task:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for range listConcurrency() {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for task := range queue {
+				task.run(ctx, labelSelector)
+			}
+		}()
+	}
+
+	for _, task := range tasks {
+		for page := range task.pages {
+			for i := range page {
+				if task.namespaces != nil && !task.namespaces.Has(page[i].GetNamespace()) {
+					continue
+				}
+				if err := visit(&page[i]); err != nil {
+					return err
+				}
+			}
+		}
+		if task.err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := failed(task); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
+}
 
-	return buffer.Bytes(), nil
+// listClusterWide reports whether namespaced resources should be listed once across the cluster and filtered
+// locally, which takes far fewer calls than listing each namespace when most of them are selected.
+func listClusterWide(ctx context.Context, restCfg *rest.Config, backup *backupObject, namespaces []string) (bool, error) {
+	if backup.kind != "ClusterBackup" || len(namespaces) < 2 {
+		return false, nil
+	}
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return false, err
+	}
+	all, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+	return float64(len(namespaces)) > clusterWideListRatio*float64(len(all.Items)), nil
+}
+
+// resourceEntryName names an exported object inside the archive, e.g. resources/deployments.apps/app1/web.yaml.
+func resourceEntryName(obj *unstructured.Unstructured, format backupv1alpha1.ExportFormat) string {
+	ext := ".yaml"
+	if format == backupv1alpha1.ExportFormatJSON {
+		ext = ".json"
+	}
+	gvk := obj.GroupVersionKind()
+	groupKind := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		groupKind += "." + gvk.Group
+	}
+	return path.Join(resourcesEntryDir, groupKind, namespaceSegment(obj.GetNamespace()), obj.GetName()+ext)
 }
 
-func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupObject) ([]byte, error) {
+func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupObject, issues *exportIssues) ([]byte, []*unstructured.Unstructured, error) {
 	if backup.spec.Snapshot == nil {
-		return []byte(""), nil
+		return []byte(""), nil, nil
 	}
 
 	enabled := true
@@ -326,12 +724,12 @@ func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupOb
 	}
 
 	if !enabled {
-		return []byte(""), nil
+		return []byte(""), nil, nil
 	}
 
 	dyn, err := dynamic.NewForConfig(restCfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	pvcSelector := backup.spec.Snapshot.PVCSelector
@@ -339,16 +737,15 @@ func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupOb
 	if pvcSelector != nil {
 		labelSelector, err = metav1.LabelSelectorAsSelector(pvcSelector)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	namespaces, err := resolveNamespaces(ctx, restCfg, backup)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	gvr := schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
 	pvcGvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "persistentvolumeclaims"}
 
 	var snapshots []*unstructured.Unstructured
@@ -356,6 +753,9 @@ func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupOb
 	for _, ns := range namespaces {
 		pvcs, err := dyn.Resource(pvcGvr).Namespace(ns).List(ctx, metav1.ListOptions{LabelSelector: labelSelector.String()})
 		if err != nil {
+			if err := issues.record(pvcGvr, ns, err); err != nil {
+				return nil, nil, err
+			}
 			continue
 		}
 		for i := range pvcs.Items {
@@ -368,7 +768,8 @@ func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupOb
 					"name":      name,
 					"namespace": ns,
 					"labels": map[string]any{
-						"backup.example.com/backup-name": backup.name,
+						labelSnapshotBackupName: backup.name,
+						labelSnapshotBackupKind: backup.kind,
 					},
 				},
 				"spec": map[string]any{
@@ -381,14 +782,14 @@ func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupOb
 				setNestedField(snapshot.Object, *backup.spec.Snapshot.VolumeSnapshotClassName, "spec", "volumeSnapshotClassName")
 			}
 
-			created, err := dyn.Resource(gvr).Namespace(ns).Create(ctx, snapshot, metav1.CreateOptions{})
+			created, err := dyn.Resource(volumeSnapshotGVR).Namespace(ns).Create(ctx, snapshot, metav1.CreateOptions{})
+			if errors.IsAlreadyExists(err) {
+				created, err = dyn.Resource(volumeSnapshotGVR).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
+			}
 			if err != nil {
-				if errors.IsAlreadyExists(err) {
-					created, err = dyn.Resource(gvr).Namespace(ns).Get(ctx, name, metav1.GetOptions{})
-					if err == nil {
-						snapshots = append(snapshots, created)
-					}
-					continue
+				err = fmt.Errorf("snapshot of PersistentVolumeClaim %s: %w", pvc.GetName(), err)
+				if err := issues.record(volumeSnapshotGVR, ns, err); err != nil {
+					return nil, nil, err
 				}
 				continue
 			}
@@ -401,7 +802,7 @@ func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupOb
 		sanitizeObject(snap)
 		out, err := sigsyaml.Marshal(snap.Object)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if len(out) == 0 {
 			continue
@@ -409,7 +810,42 @@ func exportSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupOb
 		buffer.WriteString("---\n")
 		buffer.Write(out)
 	}
-	return buffer.Bytes(), nil
+	return buffer.Bytes(), snapshots, nil
+}
+
+func waitForSnapshotsReady(ctx context.Context, restCfg *rest.Config, snapshots []*unstructured.Unstructured) error {
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return err
+	}
+
+	pending := snapshots
+	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, snapshotsReadyTimeout, true, func(ctx context.Context) (bool, error) {
+		var remaining []*unstructured.Unstructured
+		for _, snap := range pending {
+			current, err := dyn.Resource(volumeSnapshotGVR).Namespace(snap.GetNamespace()).Get(ctx, snap.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("volume snapshot %s/%s: %w", snap.GetNamespace(), snap.GetName(), err)
+			}
+			if ready, _, _ := unstructured.NestedBool(current.Object, "status", "readyToUse"); ready {
+				continue
+			}
+			if msg, _, _ := unstructured.NestedString(current.Object, "status", "error", "message"); msg != "" {
+				return false, fmt.Errorf("volume snapshot %s/%s failed: %s", snap.GetNamespace(), snap.GetName(), msg)
+			}
+			remaining = append(remaining, snap)
+		}
+		pending = remaining
+		return len(pending) == 0, nil
+	})
+	if err != nil && wait.Interrupted(err) && ctx.Err() == nil {
+		names := make([]string, 0, len(pending))
+		for _, snap := range pending {
+			names = append(names, snap.GetNamespace()+"/"+snap.GetName())
+		}
+		return &stageTimeoutError{waitingFor: "volume snapshots to become ready: " + strings.Join(names, ", "), timeout: snapshotsReadyTimeout}
+	}
+	return err
 }
 
 func resolveNamespaces(ctx context.Context, restCfg *rest.Config, backup *backupObject) ([]string, error) {
@@ -450,33 +886,60 @@ func resolveNamespaces(ctx context.Context, restCfg *rest.Config, backup *backup
 	return namespaces, nil
 }
 
-func writeTarGz(path string, files map[string][]byte) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+// artifactWriter writes the backup archive as a gzip-compressed tar stream and records every entry for the
+// manifest it appends on Close.
+type artifactWriter struct {
+	gzip     *gzip.Writer
+	tar      *tar.Writer
+	manifest artifactManifest
+	// manifestJSON is the manifest as written by Close.
+	manifestJSON []byte
+}
+
+func newArtifactWriter(w io.Writer) *artifactWriter {
+	gzipWriter := gzip.NewWriter(w)
+	return &artifactWriter{
+		gzip:     gzipWriter,
+		tar:      tar.NewWriter(gzipWriter),
+		manifest: artifactManifest{Algorithm: digestAlgorithm, Entries: []manifestEntry{}},
 	}
-	defer file.Close()
+}
 
-	gzipWriter := gzip.NewWriter(file)
-	defer gzipWriter.Close()
+func (a *artifactWriter) add(name string, data []byte) error {
+	if err := a.write(name, data); err != nil {
+		return err
+	}
+	a.manifest.Entries = append(a.manifest.Entries, newManifestEntry(name, data))
+	return nil
+}
 
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
+func (a *artifactWriter) write(name string, data []byte) error {
+	head := &tar.Header{
+		Name: name,
+		Mode: 0600,
+		Size: int64(len(data)),
+	}
+	if err := a.tar.WriteHeader(head); err != nil {
+		return err
+	}
+	_, err := a.tar.Write(data)
+	return err
+}
 
-	for name, data := range files {
-		head := &tar.Header{
-			Name: name,
-			Mode: 0600,
-			Size: int64(len(data)),
-		}
-		if err := tarWriter.WriteHeader(head); err != nil {
-			return err
-		}
-		if _, err := tarWriter.Write(data); err != nil {
-			return err
-		}
+// Close writes manifest.json and flushes the tar and gzip trailers. It does not close the underlying writer.
+func (a *artifactWriter) Close() error {
+	manifest, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return err
 	}
-	return nil
+	if err := a.write(manifestEntryName, manifest); err != nil {
+		return err
+	}
+	a.manifestJSON = manifest
+	if err := a.tar.Close(); err != nil {
+		return err
+	}
+	return a.gzip.Close()
 }
 
 func sanitizeObject(obj *unstructured.Unstructured) {
@@ -501,6 +964,15 @@ func sanitizeObject(obj *unstructured.Unstructured) {
 		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
 		unstructured.RemoveNestedField(obj.Object, "spec", "healthCheckNodePort")
 	}
+
+	if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); obj.GetKind() == "Job" && !manual {
+		// The selector and controller-uid labels are generated from the Job's UID and are immutable,
+		// so a restored Job has to let the API server generate them again. A manual selector is the
+		// user's own and is kept.
+		unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+		unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", "controller-uid")
+		unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", "batch.kubernetes.io/controller-uid")
+	}
 }
 
 func resourceSupportsList(verbs []string) bool {
@@ -580,7 +1052,10 @@ func matchAnnotations(annotations map[string]string, selector map[string]string)
 }
 
 func resourcePriority(obj *unstructured.Unstructured) int {
-	kind := obj.GetKind()
+	return kindPriority(obj.GetKind())
+}
+
+func kindPriority(kind string) int {
 	switch kind {
 	case "Namespace":
 		return 0
@@ -600,6 +1075,9 @@ func resourcePriority(obj *unstructured.Unstructured) int {
 		return 7
 	case "Service", "Ingress", "Route":
 		return 8
+	case "APIService":
+		// After the workloads and services that back aggregated APIs, before the resources they serve.
+		return 9
 	default:
 		return 100
 	}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// metadataFileSuffix names the catalog entry stored next to each artifact.
+const metadataFileSuffix = ".metadata.json"
+
+var (
+	// errNamespaceMissing is returned by createSyncedBackup for a Backup whose namespace does not exist. Catalog
+	// sync does not create namespaces; the backup is synced on the first run after the namespace is created.
+	errNamespaceMissing = errors.New("namespace does not exist")
+	// errMetadataEntryRead stops reading an archive once its metadata entry has been read.
+	errMetadataEntryRead = errors.New("metadata entry read")
+)
+
+// catalogSync counts what one catalog sync did.
+type catalogSync struct {
+	synced int
+	// skipped counts the Backups left out because their namespace does not exist, and missingNamespaces names
+	// those namespaces.
+	skipped           int
+	missingNamespaces sets.Set[string]
+}
+
+func (s *catalogSync) message() string {
+	message := fmt.Sprintf("synced %d backups from storage", s.synced)
+	if s.skipped > 0 {
+		message += fmt.Sprintf("; skipped %d whose namespace does not exist: %s", s.skipped,
+			strings.Join(sets.List(s.missingNamespaces), ", "))
+	}
+	return message
+}
+
+// catalogEntry is stored next to each artifact so catalog sync can recreate the backup resource without reading
+// the archive, which may be encrypted.
+type catalogEntry struct {
+	Kind                    string                            `json:"kind"`
+	Name                    string                            `json:"name"`
+	Namespace               string                            `json:"namespace,omitempty"`
+	ClusterID               string                            `json:"clusterID"`
+	Timestamp               string                            `json:"timestamp"`
+	Spec                    backupv1alpha1.BackupSpec         `json:"spec"`
+	Namespaces              *backupv1alpha1.NamespaceSelector `json:"namespaces,omitempty"`
+	IncludeClusterResources *bool                             `json:"includeClusterResources,omitempty"`
+	Status                  backupv1alpha1.BackupStatus       `json:"status"`
+}
+
+func buildCatalogEntry(backup *backupObject, timestamp string, status backupv1alpha1.BackupStatus) ([]byte, error) {
+	entry := catalogEntry{
+		Kind:      backup.kind,
+		Name:      backup.name,
+		Namespace: backup.namespace,
+		ClusterID: clusterID(),
+		Timestamp: timestamp,
+		Spec:      backup.spec,
+		Status:    status,
+	}
+	if backup.kind == "ClusterBackup" {
+		entry.Namespaces = backup.namespaces
+		entry.IncludeClusterResources = &backup.includeClusterResources
+	}
+	return json.MarshalIndent(entry, "", "  ")
+}
+
+// runCatalogWorker recreates Backup and ClusterBackup resources for artifacts in a storage location that no
+// resource in this cluster refers to, e.g. after the cluster was rebuilt or for artifacts written by other clusters.
+// The outcome is recorded in the location's CatalogSynced condition.
+func runCatalogWorker(ctx context.Context, c client.Client, cfg workerConfig) error {
+	if cfg.kind != "BackupStorageLocation" {
+		return fmt.Errorf("catalog-worker does not support kind %q", cfg.kind)
+	}
+	location := &backupv1alpha1.BackupStorageLocation{}
+	if err := c.Get(ctx, client.ObjectKey{Name: cfg.name}, location); err != nil {
+		return err
+	}
+
+	result, err := syncCatalog(ctx, c, location)
+	now := metav1.Now()
+	condition := metav1.Condition{
+		Type:               backupv1alpha1.StorageLocationConditionCatalogSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             backupv1alpha1.ConditionReasonSucceeded,
+		Message:            result.message(),
+		ObservedGeneration: location.Generation,
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = backupv1alpha1.ConditionReasonFailed
+		condition.Message = fmt.Sprintf("synced %d backups before failing: %v", result.synced, err)
+	}
+	updateErr := storage.PatchStatus(ctx, c, location, func(location *backupv1alpha1.BackupStorageLocation) {
+		condition.ObservedGeneration = location.Generation
+		location.Status.LastSynced = &now
+		meta.SetStatusCondition(&location.Status.Conditions, condition)
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	return err
+}
+
+// syncCatalog reports how many resources it created and which it skipped.
+func syncCatalog(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation) (*catalogSync, error) {
+	logger := ctrl.Log.WithName("catalog")
+	result := &catalogSync{missingNamespaces: sets.New[string]()}
+
+	known, err := knownArtifacts(ctx, c)
+	if err != nil {
+		return result, err
+	}
+	backend, err := storage.New(ctx, c, location)
+	if err != nil {
+		return result, err
+	}
+
+	var keys []string
+	err = backend.List(ctx, "", func(object storage.Object) error {
+		if isArtifactKey(object.Key) && !known[backend.Location(object.Key)] {
+			keys = append(keys, object.Key)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, key := range keys {
+		entry, err := loadCatalogEntry(ctx, backend, key)
+		if err != nil {
+			// One unreadable entry must not keep every other backup out of the catalog.
+			logger.Error(err, "skipping artifact", "key", key)
+			continue
+		}
+		created, err := createSyncedBackup(ctx, c, location, backend.Location(key), entry)
+		if errors.Is(err, errNamespaceMissing) {
+			result.skipped++
+			result.missingNamespaces.Insert(entry.Namespace)
+			logger.Info("skipping backup whose namespace does not exist", "name", entry.Name, "namespace", entry.Namespace,
+				"artifact", backend.Location(key))
+			continue
+		}
+		if err != nil {
+			return result, fmt.Errorf("sync %s: %w", key, err)
+		}
+		if created {
+			result.synced++
+			logger.Info("synced backup from storage", "kind", entry.Kind, "name", entry.Name, "namespace", entry.Namespace,
+				"sourceCluster", entry.ClusterID, "artifact", backend.Location(key))
+		}
+	}
+	return result, nil
+}
+
+// knownArtifacts collects the artifact locations that a Backup or ClusterBackup in the cluster already refers to.
+// A synced resource only counts once its status records the artifact, so a run that died between creating it and
+// writing its status is repaired by the next one.
+func knownArtifacts(ctx context.Context, c client.Client) (map[string]bool, error) {
+	known := map[string]bool{}
+	var backups backupv1alpha1.BackupList
+	if err := c.List(ctx, &backups); err != nil {
+		return nil, err
+	}
+	for _, backup := range backups.Items {
+		if backup.Status.ArtifactLocation != "" {
+			known[backup.Status.ArtifactLocation] = true
+		}
+	}
+	var clusterBackups backupv1alpha1.ClusterBackupList
+	if err := c.List(ctx, &clusterBackups); err != nil {
+		return nil, err
+	}
+	for _, backup := range clusterBackups.Items {
+		if backup.Status.ArtifactLocation != "" {
+			known[backup.Status.ArtifactLocation] = true
+		}
+	}
+	return known, nil
+}
+
+// isArtifactKey matches <cluster-id>/<kind>/<namespace>/<name>/<timestamp>/<artifact>, as written by storeArtifact.
+func isArtifactKey(key string) bool {
+	segments := strings.Split(key, "/")
+	if len(segments) != 6 {
+		return false
+	}
+	switch segments[1] {
+	case "backup", "clusterbackup":
+	default:
+		return false
+	}
+	file := segments[5]
+	return file == artifactFileName || file == artifactFileName+encryptedFileSuffix
+}
+
+// loadCatalogEntry reads the entry stored next to an artifact. Without one, an unencrypted archive is described by
+// its metadata entry; an encrypted one only has its key to go by.
+func loadCatalogEntry(ctx context.Context, backend storage.Backend, key string) (*catalogEntry, error) {
+	reader, err := backend.Get(ctx, key+metadataFileSuffix)
+	if storage.IsNotFound(err) {
+		if strings.HasSuffix(key, encryptedFileSuffix) {
+			return catalogEntryFromKey(key), nil
+		}
+		return catalogEntryFromArchive(ctx, backend, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(io.LimitReader(reader, maxSidecarSize))
+	if err != nil {
+		return nil, err
+	}
+	entry := &catalogEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", key+metadataFileSuffix, err)
+	}
+	return entry, nil
+}
+
+// catalogEntryFromArchive reads the metadata entry at the start of an unencrypted archive. Archives written before
+// it carried the spec yield a backup with the default spec.
+func catalogEntryFromArchive(ctx context.Context, backend storage.Backend, key string) (*catalogEntry, error) {
+	reader, err := backend.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var entry *catalogEntry
+	err = readArtifact(reader, func(name string, r io.Reader) error {
+		if name != metadataEntryName {
+			return nil
+		}
+		data, err := io.ReadAll(io.LimitReader(r, maxSidecarSize))
+		if err != nil {
+			return err
+		}
+		entry = &catalogEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			return fmt.Errorf("decode %s in %s: %w", metadataEntryName, key, err)
+		}
+		return errMetadataEntryRead
+	})
+	if err != nil && !errors.Is(err, errMetadataEntryRead) {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%s has no %s entry", key, metadataEntryName)
+	}
+	if entry.Kind == "" || entry.Name == "" || (entry.Kind == "Backup" && entry.Namespace == "") {
+		return nil, fmt.Errorf("%s in %s does not name the backup that wrote it", metadataEntryName, key)
+	}
+	entry.Status = backupv1alpha1.BackupStatus{Phase: backupv1alpha1.BackupPhaseCompleted}
+	return entry, nil
+}
+
+func catalogEntryFromKey(key string) *catalogEntry {
+	segments := strings.Split(key, "/")
+	entry := &catalogEntry{
+		Kind:      "Backup",
+		Name:      segments[3],
+		Namespace: segments[2],
+		ClusterID: segments[0],
+		Timestamp: segments[4],
+		Status:    backupv1alpha1.BackupStatus{Phase: backupv1alpha1.BackupPhaseCompleted},
+	}
+	if segments[1] == "clusterbackup" {
+		entry.Kind = "ClusterBackup"
+		entry.Namespace = ""
+	}
+	return entry
+}
+
+// createSyncedBackup creates the read-only resource for an artifact and reports whether it did. The original name
+// is kept unless a resource for another artifact holds it.
+func createSyncedBackup(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation string, entry *catalogEntry) (bool, error) {
+	spec := entry.Spec
+	spec.StorageRef = &corev1.LocalObjectReference{Name: location.Name}
+	// The resource is only a record of the artifact: it must neither expire nor take the artifact with it.
+	spec.DeletionPolicy = backupv1alpha1.BackupDeletionPolicyRetain
+	spec.TTL = nil
+	spec.RetainUntil = nil
+
+	status := entry.Status
+	status.ArtifactLocation = artifactLocation
+	status.Conditions = nil
+	status.ExpiresAt = nil
+	status.ObservedGeneration = 0
+	status.Message = fmt.Sprintf("synced from BackupStorageLocation %s, written by cluster %s", location.Name, entry.ClusterID)
+	if status.Phase == "" {
+		status.Phase = backupv1alpha1.BackupPhaseCompleted
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:    backupv1alpha1.BackupConditionCompleted,
+		Status:  metav1.ConditionTrue,
+		Reason:  backupv1alpha1.ConditionReasonSynced,
+		Message: status.Message,
+	})
+
+	objectMeta := metav1.ObjectMeta{
+		Name:        entry.Name,
+		Namespace:   entry.Namespace,
+		Labels:      map[string]string{backupv1alpha1.BackupSyncedLabel: "true"},
+		Annotations: map[string]string{backupv1alpha1.BackupSourceClusterAnnotation: entry.ClusterID, backupv1alpha1.BackupArtifactAnnotation: artifactLocation},
+	}
+
+	var obj client.Object
+	var setStatus func()
+	var statusOf func(client.Object) backupv1alpha1.BackupStatus
+	switch entry.Kind {
+	case "Backup":
+		if err := c.Get(ctx, client.ObjectKey{Name: entry.Namespace}, &corev1.Namespace{}); apierrors.IsNotFound(err) {
+			return false, errNamespaceMissing
+		} else if err != nil {
+			return false, err
+		}
+		backup := &backupv1alpha1.Backup{ObjectMeta: objectMeta, Spec: spec}
+		obj, setStatus = backup, func() { backup.Status = status }
+		statusOf = func(obj client.Object) backupv1alpha1.BackupStatus { return obj.(*backupv1alpha1.Backup).Status }
+	case "ClusterBackup":
+		backup := &backupv1alpha1.ClusterBackup{ObjectMeta: objectMeta, Spec: backupv1alpha1.ClusterBackupSpec{
+			BackupSpec:              spec,
+			Namespaces:              entry.Namespaces,
+			IncludeClusterResources: entry.IncludeClusterResources,
+		}}
+		obj, setStatus = backup, func() { backup.Status.BackupStatus = status }
+		statusOf = func(obj client.Object) backupv1alpha1.BackupStatus {
+			return obj.(*backupv1alpha1.ClusterBackup).Status.BackupStatus
+		}
+	default:
+		return false, fmt.Errorf("unsupported backup kind %q", entry.Kind)
+	}
+
+	synced := &syncedObject{obj: obj, setStatus: setStatus, statusOf: statusOf, artifactLocation: artifactLocation}
+	// A resource of this cluster with the same name and no artifact yet is most likely the backup that is still
+	// writing this artifact. Any other resource holding the name refers to another artifact, e.g. a new backup
+	// created under the name of a deleted one that retained its artifact, so the artifact is synced under another name.
+	created, err := synced.create(ctx, c, entry.ClusterID == clusterID())
+	if errors.Is(err, errNameTaken) {
+		obj.SetName(syncedBackupName(entry.Name, artifactLocation))
+		created, err = synced.create(ctx, c, false)
+	}
+	return created, err
+}
+
+// errNameTaken is returned by syncedObject.create when the name belongs to a resource for another artifact.
+var errNameTaken = errors.New("name is taken by a backup of another artifact")
+
+// syncedObject is a resource catalog sync creates for an artifact, together with the status it records.
+type syncedObject struct {
+	obj              client.Object
+	setStatus        func()
+	statusOf         func(client.Object) backupv1alpha1.BackupStatus
+	artifactLocation string
+}
+
+// create creates the resource and writes its status, and reports whether it did. A resource of the same name that
+// was created for the artifact but never got its status is repaired. mayBeWriting treats a resource with no
+// artifact yet as the backup that is still writing this one.
+func (s *syncedObject) create(ctx context.Context, c client.Client, mayBeWriting bool) (bool, error) {
+	err := c.Create(ctx, s.obj)
+	if apierrors.IsAlreadyExists(err) {
+		existing := s.obj.DeepCopyObject().(client.Object)
+		if err := c.Get(ctx, client.ObjectKeyFromObject(s.obj), existing); err != nil {
+			return false, err
+		}
+		status := s.statusOf(existing)
+		switch {
+		case status.ArtifactLocation == s.artifactLocation:
+			return false, nil
+		case status.ArtifactLocation == "" && existing.GetAnnotations()[backupv1alpha1.BackupArtifactAnnotation] == s.artifactLocation:
+			// An earlier run created it and stopped before writing its status.
+			return true, s.writeStatus(ctx, c)
+		case status.ArtifactLocation == "" && !isSyncedObject(existing) && mayBeWriting:
+			return false, nil
+		default:
+			return false, errNameTaken
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := s.writeStatus(ctx, c); err != nil {
+		// Without its status the resource records no artifact and is never run; remove it so the next run retries.
+		if deleteErr := c.Delete(ctx, s.obj); client.IgnoreNotFound(deleteErr) != nil {
+			return false, errors.Join(err, deleteErr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// writeStatus records the artifact in the resource's status. The backup controller adds its finalizer right after
+// the resource is created, so a conflict is retried against the latest version.
+func (s *syncedObject) writeStatus(ctx context.Context, c client.Client) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(s.obj), s.obj); err != nil {
+			return err
+		}
+		s.setStatus()
+		return c.Status().Update(ctx, s.obj)
+	})
+}
+
+func isSyncedObject(obj client.Object) bool {
+	return obj.GetLabels()[backupv1alpha1.BackupSyncedLabel] == "true"
+}
+
+// syncedBackupName derives a stable alternative name from the artifact location.
+func syncedBackupName(name, artifactLocation string) string {
+	sum := sha256.Sum256([]byte(artifactLocation))
+	suffix := "-" + hex.EncodeToString(sum[:])[:8]
+	if len(name)+len(suffix) > 253 {
+		name = name[:253-len(suffix)]
+	}
+	return name + suffix
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCatalogEntryFromArchive(t *testing.T) {
+	ctx := context.Background()
+	backend := storage.NewMemory(t.Name())
+	backup := &backupObject{
+		kind:      "Backup",
+		name:      "app1-backup",
+		namespace: "app1",
+		spec:      backupv1alpha1.BackupSpec{ExecutionMode: backupv1alpha1.ExecutionModeSync},
+	}
+	location := &backupv1alpha1.BackupStorageLocation{Spec: backupv1alpha1.BackupStorageLocationSpec{Type: memoryStorageType}}
+	metadata, err := buildBackupMetadata(backup, location, "20260102T030405Z")
+	if err != nil {
+		t.Fatalf("buildBackupMetadata: %v", err)
+	}
+	key := "cluster-a/backup/app1/app1-backup/20260102T030405Z/" + artifactFileName
+	if _, err := backend.Put(ctx, key, func(w io.Writer) error {
+		archive := newArtifactWriter(w)
+		if err := archive.add(metadataEntryName, metadata); err != nil {
+			return err
+		}
+		return archive.Close()
+	}); err != nil {
+		t.Fatalf("put artifact: %v", err)
+	}
+
+	entry, err := loadCatalogEntry(ctx, backend, key)
+	if err != nil {
+		t.Fatalf("loadCatalogEntry: %v", err)
+	}
+	if entry.Kind != "Backup" || entry.Name != "app1-backup" || entry.Namespace != "app1" {
+		t.Errorf("entry = %s %s/%s, want Backup app1/app1-backup", entry.Kind, entry.Namespace, entry.Name)
+	}
+	if entry.Spec.ExecutionMode != backupv1alpha1.ExecutionModeSync {
+		t.Errorf("entry executionMode = %q, want %q", entry.Spec.ExecutionMode, backupv1alpha1.ExecutionModeSync)
+	}
+	if entry.Status.Phase != backupv1alpha1.BackupPhaseCompleted {
+		t.Errorf("entry phase = %s, want %s", entry.Status.Phase, backupv1alpha1.BackupPhaseCompleted)
+	}
+}
+
+// syncTestClient returns a client holding namespace app1 and the given objects.
+func syncTestClient(objects ...client.Object) client.Client {
+	objects = append(objects, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app1"}})
+	return fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&backupv1alpha1.Backup{}).
+		WithObjects(objects...).Build()
+}
+
+func syncTestEntry() *catalogEntry {
+	return &catalogEntry{Kind: "Backup", Name: "app1-backup", Namespace: "app1", ClusterID: clusterID()}
+}
+
+func TestCreateSyncedBackupNameTaken(t *testing.T) {
+	ctx := context.Background()
+	// A backup that retained its artifact was deleted and a new one of the same name wrote another artifact.
+	existing := &backupv1alpha1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: "app1-backup", Namespace: "app1"},
+		Status:     backupv1alpha1.BackupStatus{ArtifactLocation: "memory://new/backup.tar.gz"},
+	}
+	c := syncTestClient(existing)
+	location := &backupv1alpha1.BackupStorageLocation{ObjectMeta: metav1.ObjectMeta{Name: "memory"}}
+	artifactLocation := "memory://old/backup.tar.gz"
+
+	created, err := createSyncedBackup(ctx, c, location, artifactLocation, syncTestEntry())
+	if err != nil || !created {
+		t.Fatalf("createSyncedBackup = %v, %v, want it to create the backup", created, err)
+	}
+	synced := &backupv1alpha1.Backup{}
+	key := client.ObjectKey{Namespace: "app1", Name: syncedBackupName("app1-backup", artifactLocation)}
+	if err := c.Get(ctx, key, synced); err != nil {
+		t.Fatalf("get synced backup: %v", err)
+	}
+	if synced.Status.ArtifactLocation != artifactLocation {
+		t.Errorf("synced artifactLocation = %q, want %q", synced.Status.ArtifactLocation, artifactLocation)
+	}
+}
+
+func TestCreateSyncedBackupRepairsStatus(t *testing.T) {
+	ctx := context.Background()
+	artifactLocation := "memory://old/backup.tar.gz"
+	// An earlier run created the backup and stopped before writing its status.
+	existing := &backupv1alpha1.Backup{ObjectMeta: metav1.ObjectMeta{
+		Name:        "app1-backup",
+		Namespace:   "app1",
+		Labels:      map[string]string{backupv1alpha1.BackupSyncedLabel: "true"},
+		Annotations: map[string]string{backupv1alpha1.BackupArtifactAnnotation: artifactLocation},
+	}}
+	c := syncTestClient(existing)
+	location := &backupv1alpha1.BackupStorageLocation{ObjectMeta: metav1.ObjectMeta{Name: "memory"}}
+
+	known, err := knownArtifacts(ctx, c)
+	if err != nil {
+		t.Fatalf("knownArtifacts: %v", err)
+	}
+	if known[artifactLocation] {
+		t.Fatalf("artifact of a backup without status counts as known")
+	}
+	created, err := createSyncedBackup(ctx, c, location, artifactLocation, syncTestEntry())
+	if err != nil || !created {
+		t.Fatalf("createSyncedBackup = %v, %v, want it to repair the backup", created, err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(existing), existing); err != nil {
+		t.Fatalf("get backup: %v", err)
+	}
+	if existing.Status.ArtifactLocation != artifactLocation || existing.Status.Phase != backupv1alpha1.BackupPhaseCompleted {
+		t.Errorf("status = %s %q, want Completed %q", existing.Status.Phase, existing.Status.ArtifactLocation, artifactLocation)
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Encrypted artifacts start with encryptionMagic and a one-line JSON encryptionHeader, followed by the gzip stream
+// sealed in encryptionChunkSize chunks. Each chunk uses the header's nonce prefix, a chunk counter, and a final-chunk
+// flag as its nonce, so chunks cannot be reordered, dropped, or truncated without failing authentication. The
+// header is authenticated as additional data of every chunk.
+const (
+	encryptionMagic           = "BACKUP-OPERATOR-ENCRYPTED\n"
+	encryptionAlgorithm       = "AES-256-GCM"
+	encryptionChunkSize       = 64 << 10
+	encryptionNoncePrefixSize = 7
+	encryptedFileSuffix       = ".enc"
+)
+
+// encryptionHeader is the unencrypted metadata a restore needs to pick the right key.
+type encryptionHeader struct {
+	Algorithm   string `json:"algorithm"`
+	KeyID       string `json:"keyID"`
+	NoncePrefix []byte `json:"noncePrefix"`
+	ChunkSize   int    `json:"chunkSize"`
+}
+
+// encryptionSpecFor returns the encryption settings for a backup; the backup's own take precedence over those of
+// its storage location. It returns nil when artifacts are stored in plaintext.
+func encryptionSpecFor(spec backupv1alpha1.BackupSpec, storage *backupv1alpha1.BackupStorageLocation) *backupv1alpha1.EncryptionSpec {
+	if spec.Encryption != nil {
+		return spec.Encryption
+	}
+	return storage.Spec.Encryption
+}
+
+// encryptionSecret holds the raw entries of an encryption Secret. Entries are only parsed when needed, so one
+// malformed entry does not break artifacts sealed with the others.
+type encryptionSecret struct {
+	name string
+	data map[string][]byte
+}
+
+func loadEncryptionSecret(ctx context.Context, c client.Client, enc *backupv1alpha1.EncryptionSpec) (*encryptionSecret, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Name: enc.SecretRef.Name, Namespace: enc.SecretRef.Namespace}
+	if key.Namespace == "" {
+		key.Namespace = operatorNamespace()
+	}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("encryption secret: %w", err)
+	}
+	return &encryptionSecret{name: key.Namespace + "/" + key.Name, data: secret.Data}, nil
+}
+
+// key parses the key with the given ID.
+func (s *encryptionSecret) key(id string) ([]byte, bool, error) {
+	value, ok := s.data[id]
+	if !ok {
+		return nil, false, nil
+	}
+	material, err := parseEncryptionKey(value)
+	if err != nil {
+		return nil, true, fmt.Errorf("encryption key %q in secret %s: %w", id, s.name, err)
+	}
+	return material, true, nil
+}
+
+// activeEncryptionKey returns the key that encrypts new artifacts.
+func activeEncryptionKey(ctx context.Context, c client.Client, enc *backupv1alpha1.EncryptionSpec) (string, []byte, error) {
+	secret, err := loadEncryptionSecret(ctx, c, enc)
+	if err != nil {
+		return "", nil, err
+	}
+	if enc.KeyID != "" {
+		material, ok, err := secret.key(enc.KeyID)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			return "", nil, fmt.Errorf("encryption key %q not found in secret %s", enc.KeyID, secret.name)
+		}
+		return enc.KeyID, material, nil
+	}
+
+	// Without a key ID the Secret must hold a single usable key; entries that are not keys are skipped.
+	logger := ctrl.Log.WithName("encryption")
+	var ids []string
+	var material []byte
+	for id := range secret.data {
+		key, _, err := secret.key(id)
+		if err != nil {
+			logger.Error(err, "skipping malformed encryption key")
+			continue
+		}
+		ids = append(ids, id)
+		material = key
+	}
+	if len(ids) != 1 {
+		sort.Strings(ids)
+		return "", nil, fmt.Errorf("encryption keyID is required when secret %s holds %d valid keys %v", secret.name, len(ids), ids)
+	}
+	return ids[0], material, nil
+}
+
+// parseEncryptionKey accepts a 32-byte key either raw or base64-encoded.
+func parseEncryptionKey(value []byte) ([]byte, error) {
+	if len(value) == 32 {
+		return value, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(value)))
+	if err == nil && len(decoded) == 32 {
+		return decoded, nil
+	}
+	return nil, fmt.Errorf("expected a 32-byte AES-256 key")
+}
+
+// encryptingWriter seals everything written to it. Close must be called to write the final chunk.
+type encryptingWriter struct {
+	out    io.Writer
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	buf    []byte
+	chunk  uint32
+}
+
+func newEncryptingWriter(out io.Writer, keyID string, key []byte) (*encryptingWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, encryptionNoncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	header, err := json.Marshal(encryptionHeader{
+		Algorithm:   encryptionAlgorithm,
+		KeyID:       keyID,
+		NoncePrefix: prefix,
+		ChunkSize:   encryptionChunkSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(out, encryptionMagic); err != nil {
+		return nil, err
+	}
+	if _, err := out.Write(append(header, '\n')); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{out: out, aead: aead, header: header, prefix: prefix,
+		buf: make([]byte, 0, encryptionChunkSize+1)}, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, because the last chunk has to carry the final flag.
+		if len(w.buf) == encryptionChunkSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):encryptionChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the final chunk. It does not close the underlying writer.
+func (w *encryptingWriter) Close() error {
+	return w.seal(true)
+}
+
+func (w *encryptingWriter) seal(final bool) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.prefix, w.chunk, final), w.buf, w.header)
+	if _, err := w.out.Write(sealed); err != nil {
+		return err
+	}
+	w.chunk++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// decryptingReader opens the chunks written by encryptingWriter.
+type decryptingReader struct {
+	in     *bufio.Reader
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	sealed []byte
+	plain  []byte
+	chunk  uint32
+	done   bool
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *decryptingReader) open() error {
+	n, err := io.ReadFull(r.in, r.sealed)
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		r.done = true
+	case err != nil:
+		return err
+	default:
+		// A full chunk is the last one only if nothing follows it.
+		if _, err := r.in.Peek(1); err == io.EOF {
+			r.done = true
+		}
+	}
+	plain, err := r.aead.Open(r.sealed[:0], chunkNonce(r.prefix, r.chunk, r.done), r.sealed[:n], r.header)
+	if err != nil {
+		return fmt.Errorf("decrypt artifact chunk %d: artifact is truncated, corrupted, or encrypted with a different key", r.chunk)
+	}
+	r.chunk++
+	r.plain = plain
+	return nil
+}
+
+// decryptArtifact returns a reader over the plaintext of an artifact. Plaintext artifacts pass through unchanged;
+// encrypted ones are decrypted with the key named in their header, looked up through keys.
+func decryptArtifact(in io.Reader, keys func(keyID string) ([]byte, error)) (io.Reader, *backupv1alpha1.ArtifactEncryption, error) {
+	buffered := bufio.NewReader(in)
+	magic, err := buffered.Peek(len(encryptionMagic))
+	if err != nil || string(magic) != encryptionMagic {
+		return buffered, nil, nil
+	}
+	if _, err := buffered.Discard(len(encryptionMagic)); err != nil {
+		return nil, nil, err
+	}
+	line, err := buffered.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, fmt.Errorf("read encryption header: %w", err)
+	}
+	headerBytes := bytes.TrimSuffix(line, []byte("\n"))
+	var header encryptionHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, nil, fmt.Errorf("parse encryption header: %w", err)
+	}
+	if header.Algorithm != encryptionAlgorithm {
+		return nil, nil, fmt.Errorf("unsupported artifact encryption algorithm %q", header.Algorithm)
+	}
+	if len(header.NoncePrefix) != encryptionNoncePrefixSize || header.ChunkSize <= 0 {
+		return nil, nil, fmt.Errorf("invalid encryption header")
+	}
+
+	key, err := keys(header.KeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := &decryptingReader{
+		in:     buffered,
+		aead:   aead,
+		header: headerBytes,
+		prefix: header.NoncePrefix,
+		sealed: make([]byte, header.ChunkSize+aead.Overhead()),
+	}
+	return reader, &backupv1alpha1.ArtifactEncryption{Algorithm: header.Algorithm, KeyID: header.KeyID}, nil
+}
+
+// openBackupArtifact opens a stored artifact and transparently decrypts it using the backup's encryption Secret,
+// which must still hold the key the artifact was written with.
+func openBackupArtifact(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, spec backupv1alpha1.BackupSpec, artifactLocation string) (io.ReadCloser, error) {
+	artifact, err := openArtifact(ctx, c, storage, artifactLocation)
+	if err != nil {
+		return nil, err
+	}
+	reader, _, err := decryptArtifact(artifact, artifactKeys(ctx, c, storage, spec))
+	if err != nil {
+		_ = artifact.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, artifact}, nil
+}
+
+// artifactKeys looks up decryption keys in the encryption Secret of a backup.
+func artifactKeys(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, spec backupv1alpha1.BackupSpec) func(keyID string) ([]byte, error) {
+	return func(keyID string) ([]byte, error) {
+		enc := encryptionSpecFor(spec, storage)
+		if enc == nil {
+			return nil, fmt.Errorf("artifact is encrypted with key %q but no encryption secret is configured", keyID)
+		}
+		secret, err := loadEncryptionSecret(ctx, c, enc)
+		if err != nil {
+			return nil, err
+		}
+		key, ok, err := secret.key(keyID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("encryption key %q not found in secret %s; retired keys must stay in the secret", keyID, secret.name)
+		}
+		return key, nil
+	}
+}
+
+// sealArtifact runs write against w, encrypting its output with key when one is given.
+func sealArtifact(w io.Writer, keyID string, key []byte, write func(io.Writer) error) error {
+	if key == nil {
+		return write(w)
+	}
+	encrypted, err := newEncryptingWriter(w, keyID, key)
+	if err != nil {
+		return err
+	}
+	if err := write(encrypted); err != nil {
+		return err
+	}
+	return encrypted.Close()
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, chunk uint32, final bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, chunk)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
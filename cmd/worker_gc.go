@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/resolve"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func runGCWorker(ctx context.Context, c client.Client, restCfg *rest.Config, cfg workerConfig) error {
+	backup, err := loadBackupObject(ctx, c, cfg)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if err := cleanupBackupData(ctx, c, restCfg, backup); err != nil {
+		return err
+	}
+
+	// Everything is cleaned up already, so the deletion finalizer has nothing left to do.
+	if err := backup.removeFinalizer(); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return client.IgnoreNotFound(backup.deleteObject())
+}
+
+// runCleanupWorker removes stored data for a backup that is being deleted and releases its finalizer.
+// Failures are recorded on the backup instead of holding the deletion.
+func runCleanupWorker(ctx context.Context, c client.Client, restCfg *rest.Config, cfg workerConfig) error {
+	backup, err := loadBackupObject(ctx, c, cfg)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if err := cleanupBackupData(ctx, c, restCfg, backup); err != nil {
+		if err := backup.setCondition(backupv1alpha1.BackupConditionArtifactsDeleted, metav1.ConditionFalse,
+			backupv1alpha1.ConditionReasonFailed, err.Error()); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+	} else if err := backup.setCondition(backupv1alpha1.BackupConditionArtifactsDeleted, metav1.ConditionTrue,
+		backupv1alpha1.ConditionReasonSucceeded, "artifact and volume snapshots deleted"); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	return client.IgnoreNotFound(backup.removeFinalizer())
+}
+
+// cleanupBackupData deletes the backup's VolumeSnapshots and then its artifact, so the snapshot list in the
+// artifact is still readable if snapshot deletion has to be retried.
+func cleanupBackupData(ctx context.Context, c client.Client, restCfg *rest.Config, backup *backupObject) error {
+	logger := ctrl.Log.WithName("cleanup")
+
+	var storage *backupv1alpha1.BackupStorageLocation
+	var recorded []*unstructured.Unstructured
+	if backup.status.ArtifactLocation != "" {
+		storageName := ""
+		if backup.spec.StorageRef != nil {
+			storageName = backup.spec.StorageRef.Name
+		}
+		var err error
+		storage, err = resolve.StorageLocation(ctx, c, storageName)
+		if err != nil {
+			return err
+		}
+		recorded, err = recordedSnapshots(ctx, c, storage, backup)
+		if err != nil {
+			// The artifact may already be gone; the snapshot labels still find what the worker created.
+			logger.Info("unable to read snapshots.yaml from artifact", "location", backup.status.ArtifactLocation, "error", err.Error())
+		}
+	}
+
+	deleted, err := deleteBackupSnapshots(ctx, restCfg, backup, recorded)
+	if err != nil {
+		return fmt.Errorf("delete volume snapshots: %w", err)
+	}
+	logger.Info("deleted volume snapshots", "count", deleted)
+
+	if storage != nil {
+		if err := deleteArtifact(ctx, c, storage, backup.status.ArtifactLocation); err != nil {
+			return fmt.Errorf("delete artifact %s: %w", backup.status.ArtifactLocation, err)
+		}
+		logger.Info("deleted artifact", "location", backup.status.ArtifactLocation)
+	}
+	return nil
+}
+
+// recordedSnapshots returns the VolumeSnapshots listed in the artifact's snapshots.yaml.
+func recordedSnapshots(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, backup *backupObject) ([]*unstructured.Unstructured, error) {
+	artifact, err := openBackupArtifact(ctx, c, storage, backup.spec, backup.status.ArtifactLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer artifact.Close()
+
+	var snapshots []*unstructured.Unstructured
+	err = readArtifact(artifact, func(name string, entry io.Reader) error {
+		if name != "snapshots.yaml" {
+			return nil
+		}
+		objs, err := decodeYAMLDocuments(entry)
+		snapshots = objs
+		return err
+	})
+	return snapshots, err
+}
+
+// deleteBackupSnapshots removes the recorded VolumeSnapshots and any others labelled for the backup. Snapshots
+// created before the kind label existed only carry the backup name, so they are matched by excluding the other kind.
+func deleteBackupSnapshots(ctx context.Context, restCfg *rest.Config, backup *backupObject, recorded []*unstructured.Unstructured) (int, error) {
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return 0, err
+	}
+
+	otherKind := "ClusterBackup"
+	if backup.kind == "ClusterBackup" {
+		otherKind = "Backup"
+	}
+	selector := fmt.Sprintf("%s=%s,%s!=%s", labelSnapshotBackupName, backup.name, labelSnapshotBackupKind, otherKind)
+
+	var snapshotClient dynamic.ResourceInterface = dyn.Resource(volumeSnapshotGVR)
+	if backup.kind == "Backup" {
+		snapshotClient = dyn.Resource(volumeSnapshotGVR).Namespace(backup.namespace)
+	}
+	snapshots := recorded
+	list, err := snapshotClient.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return 0, err
+		}
+		// VolumeSnapshot CRDs are not installed, so there is nothing to clean up.
+		return 0, nil
+	}
+	for i := range list.Items {
+		snapshots = append(snapshots, &list.Items[i])
+	}
+
+	deleted := 0
+	seen := map[string]bool{}
+	for _, snap := range snapshots {
+		key := snap.GetNamespace() + "/" + snap.GetName()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		err := dyn.Resource(volumeSnapshotGVR).Namespace(snap.GetNamespace()).Delete(ctx, snap.GetName(), metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Every artifact ends with a manifest.json that lists the entries before it. The SHA-256 digest of the stored
+// artifact, after compression and encryption, goes to BackupStatus.ArtifactDigest and to a sidecar file next to
+// the artifact in sha256sum format, so the artifact can also be checked with `sha256sum -c`.
+const (
+	manifestEntryName = "manifest.json"
+	digestAlgorithm   = "sha256"
+	digestFileSuffix  = ".sha256"
+)
+
+// artifactManifest lists every entry of an artifact except itself.
+type artifactManifest struct {
+	Algorithm string          `json:"algorithm"`
+	Entries   []manifestEntry `json:"entries"`
+}
+
+type manifestEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func newManifestEntry(name string, data []byte) manifestEntry {
+	sum := sha256.Sum256(data)
+	return manifestEntry{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// integrityError reports an artifact that does not match its digest or manifest, as opposed to one that could not
+// be read at all.
+type integrityError struct {
+	message string
+}
+
+func (e *integrityError) Error() string {
+	return e.message
+}
+
+func isIntegrityError(err error) bool {
+	var target *integrityError
+	return errors.As(err, &target)
+}
+
+func formatDigest(sum []byte) string {
+	return digestAlgorithm + ":" + hex.EncodeToString(sum)
+}
+
+// digestFileContent renders the digest sidecar of an artifact.
+func digestFileContent(digest, artifactLocation string) []byte {
+	return []byte(fmt.Sprintf("%s  %s\n", strings.TrimPrefix(digest, digestAlgorithm+":"), path.Base(artifactLocation)))
+}
+
+// parseDigestFile reads the digest back from a sidecar written by digestFileContent.
+func parseDigestFile(data []byte) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("digest file is empty")
+	}
+	sum, err := hex.DecodeString(fields[0])
+	if err != nil || len(sum) != sha256.Size {
+		return "", fmt.Errorf("digest file does not hold a SHA-256 digest")
+	}
+	return formatDigest(sum), nil
+}
+
+// integrityReport describes what verifyArtifactIntegrity was able to check.
+type integrityReport struct {
+	digest      string
+	entries     int
+	hasDigest   bool
+	hasManifest bool
+	// manifest holds the raw manifest.json, which is what artifact signatures cover.
+	manifest []byte
+	// listed maps the name of each entry in the verified manifest to its digest.
+	listed map[string]manifestEntry
+}
+
+func (r *integrityReport) verified() bool {
+	return r.hasDigest || r.hasManifest
+}
+
+func (r *integrityReport) message() string {
+	switch {
+	case r.hasDigest && r.hasManifest:
+		return fmt.Sprintf("artifact digest %s and %d manifest entries verified", r.digest, r.entries)
+	case r.hasDigest:
+		return fmt.Sprintf("artifact digest %s verified; the artifact has no manifest", r.digest)
+	case r.hasManifest:
+		return fmt.Sprintf("%d manifest entries verified; no artifact digest is recorded", r.entries)
+	default:
+		return "artifact predates integrity metadata and could not be verified"
+	}
+}
+
+// verifyArtifactIntegrity reads the whole artifact once without applying anything. It checks the digest of the
+// stored bytes against the one recorded on the backup, falling back to the digest sidecar, and every entry against
+// the manifest. Mismatches are returned as integrityError together with the report, so a caller that ignores them
+// can still check the signature. Artifacts written before manifests were introduced pass with an unverified report.
+func verifyArtifactIntegrity(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, spec backupv1alpha1.BackupSpec, status backupv1alpha1.BackupStatus) (*integrityReport, error) {
+	expected := status.ArtifactDigest
+	if expected == "" {
+		sidecar, err := loadArtifactSidecar(ctx, c, storage, status.ArtifactLocation, digestFileSuffix)
+		if err != nil {
+			return nil, fmt.Errorf("read artifact digest: %w", err)
+		}
+		if sidecar != nil {
+			if expected, err = parseDigestFile(sidecar); err != nil {
+				return nil, &integrityError{message: err.Error()}
+			}
+		}
+	}
+
+	artifact, err := openArtifact(ctx, c, storage, status.ArtifactLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer artifact.Close()
+
+	hash := sha256.New()
+	raw := io.TeeReader(artifact, hash)
+	report := &integrityReport{}
+	var manifest *artifactManifest
+	seen := map[string]manifestEntry{}
+	readErr := func() error {
+		plaintext, _, err := decryptArtifact(raw, artifactKeys(ctx, c, storage, spec))
+		if err != nil {
+			return err
+		}
+		return readArtifact(plaintext, func(name string, entry io.Reader) error {
+			if name == manifestEntryName {
+				data, err := io.ReadAll(entry)
+				if err != nil {
+					return err
+				}
+				report.manifest = data
+				manifest = &artifactManifest{}
+				return json.Unmarshal(data, manifest)
+			}
+			entryHash := sha256.New()
+			size, err := io.Copy(entryHash, entry)
+			if err != nil {
+				return err
+			}
+			seen[name] = manifestEntry{Name: name, Size: size, SHA256: hex.EncodeToString(entryHash.Sum(nil))}
+			return nil
+		})
+	}()
+	// Hash whatever the archive reader left unread, so the digest covers the whole object even when the archive
+	// itself is damaged.
+	if _, err := io.Copy(io.Discard, raw); err != nil {
+		return nil, err
+	}
+
+	report.digest = formatDigest(hash.Sum(nil))
+	if expected != "" {
+		if report.digest != expected {
+			return report, &integrityError{message: fmt.Sprintf("artifact digest %s does not match recorded digest %s",
+				report.digest, expected)}
+		}
+		report.hasDigest = true
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("read artifact: %w", readErr)
+	}
+	if manifest == nil {
+		return report, nil
+	}
+	if err := checkManifest(manifest, seen); err != nil {
+		return report, err
+	}
+	report.hasManifest = true
+	report.entries = len(manifest.Entries)
+	report.listed = make(map[string]manifestEntry, len(manifest.Entries))
+	for _, entry := range manifest.Entries {
+		report.listed[entry.Name] = entry
+	}
+	return report, nil
+}
+
+// entryVerifier checks each entry the restore reads against the manifest verifyArtifactIntegrity checked, before
+// anything from it is applied. The artifact is read twice, so without this one replaced in storage between the
+// reads would be restored unverified.
+type entryVerifier struct {
+	manifest []byte
+	listed   map[string]manifestEntry
+	seen     int
+}
+
+// newEntryVerifier returns nil, which verifies nothing, when the artifact had no verified manifest.
+func newEntryVerifier(report *integrityReport) *entryVerifier {
+	if report == nil || !report.hasManifest {
+		return nil
+	}
+	return &entryVerifier{manifest: report.manifest, listed: report.listed}
+}
+
+// verify reads the entry in full and returns it once it matches the manifest. Entries hold a single object, so
+// this buffers little.
+func (v *entryVerifier) verify(name string, entry io.Reader) (io.Reader, error) {
+	if v == nil {
+		return entry, nil
+	}
+	data, err := io.ReadAll(entry)
+	if err != nil {
+		return nil, err
+	}
+	if name == manifestEntryName {
+		if !bytes.Equal(data, v.manifest) {
+			return nil, &integrityError{message: "artifact changed after it was verified: manifest differs"}
+		}
+		return bytes.NewReader(data), nil
+	}
+	want, ok := v.listed[name]
+	if got := newManifestEntry(name, data); !ok || got != want {
+		return nil, &integrityError{message: fmt.Sprintf("artifact changed after it was verified: entry %s does not match the manifest", name)}
+	}
+	v.seen++
+	return bytes.NewReader(data), nil
+}
+
+// complete checks that no entry listed in the manifest went missing.
+func (v *entryVerifier) complete() error {
+	if v == nil || v.seen == len(v.listed) {
+		return nil
+	}
+	return &integrityError{message: fmt.Sprintf("artifact changed after it was verified: read %d of %d manifest entries", v.seen, len(v.listed))}
+}
+
+// checkManifest compares the entries read from an artifact with those its manifest lists.
+func checkManifest(manifest *artifactManifest, seen map[string]manifestEntry) error {
+	if manifest.Algorithm != digestAlgorithm {
+		return &integrityError{message: fmt.Sprintf("unsupported manifest digest algorithm %q", manifest.Algorithm)}
+	}
+	var problems []string
+	listed := make(map[string]bool, len(manifest.Entries))
+	for _, want := range manifest.Entries {
+		listed[want.Name] = true
+		got, ok := seen[want.Name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is missing", want.Name))
+		case got.Size != want.Size:
+			problems = append(problems, fmt.Sprintf("%s has %d bytes, manifest lists %d", want.Name, got.Size, want.Size))
+		case got.SHA256 != want.SHA256:
+			problems = append(problems, fmt.Sprintf("%s has SHA-256 %s, manifest lists %s", want.Name, got.SHA256, want.SHA256))
+		}
+	}
+	for name := range seen {
+		if !listed[name] {
+			problems = append(problems, fmt.Sprintf("%s is not listed in the manifest", name))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	const maxProblems = 5
+	message := fmt.Sprintf("artifact does not match its manifest: %s", strings.Join(problems[:min(len(problems), maxProblems)], "; "))
+	if len(problems) > maxProblems {
+		message += fmt.Sprintf(" and %d more", len(problems)-maxProblems)
+	}
+	return &integrityError{message: message}
+}
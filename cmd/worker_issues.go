@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	issueLevelWarning = "warning"
+	issueLevelError   = "error"
+)
+
+// exportIssue is one entry of the artifact's errors.json: something the backup could not capture.
+type exportIssue struct {
+	// Resource is the group/version/resource that failed, e.g. apps/v1/deployments.
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	Level     string `json:"level"`
+	Error     string `json:"error"`
+	// Fatal marks the issue that failed the backup under the Fail error policy.
+	Fatal bool `json:"fatal"`
+}
+
+// exportIssues collects the issues of one backup run. It is not safe for concurrent use.
+type exportIssues struct {
+	policy backupv1alpha1.BackupErrorPolicy
+	items  []exportIssue
+}
+
+func newExportIssues(policy backupv1alpha1.BackupErrorPolicy) *exportIssues {
+	return &exportIssues{policy: policy, items: []exportIssue{}}
+}
+
+// record adds an issue for gvr in namespace. It returns an error when the error policy makes the issue fatal,
+// in which case the caller must stop.
+func (e *exportIssues) record(gvr schema.GroupVersionResource, namespace string, err error) error {
+	issue := exportIssue{
+		Resource:  path.Join(gvr.GroupVersion().String(), gvr.Resource),
+		Namespace: namespace,
+		Level:     issueLevel(err),
+		Error:     err.Error(),
+	}
+	if issue.Level == issueLevelError && e.policy == backupv1alpha1.BackupErrorPolicyFail {
+		issue.Fatal = true
+	}
+	e.items = append(e.items, issue)
+
+	if !issue.Fatal {
+		return nil
+	}
+	if namespace == "" {
+		return fmt.Errorf("%s: %w", issue.Resource, err)
+	}
+	return fmt.Errorf("%s in namespace %s: %w", issue.Resource, namespace, err)
+}
+
+func (e *exportIssues) count(level string) int32 {
+	var n int32
+	for _, issue := range e.items {
+		if issue.Level == level {
+			n++
+		}
+	}
+	return n
+}
+
+func (e *exportIssues) marshal() ([]byte, error) {
+	return json.MarshalIndent(e.items, "", "  ")
+}
+
+// issueLevel downgrades errors that do not mean data is missing, such as a resource type removed between
+// discovery and listing, to warnings.
+func issueLevel(err error) string {
+	if errors.IsNotFound(err) || errors.IsMethodNotSupported(err) {
+		return issueLevelWarning
+	}
+	return issueLevelError
+}
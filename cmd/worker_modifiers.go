@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// resourceModifiersKey is the ConfigMap key that holds the resource modifier rules.
+const resourceModifiersKey = "resourceModifiers.yaml"
+
+// resourceModifiers are the rules a restore applies to each object before writing it, e.g. to point images at
+// another registry or scale workloads down. Every matching rule is applied, in order.
+type resourceModifiers struct {
+	Rules []modifierRule `json:"rules"`
+}
+
+// modifierRule patches the objects it matches. Exactly one of the patches is set.
+type modifierRule struct {
+	// Name identifies the rule in the restore results; it defaults to rule-<index>.
+	Name                string          `json:"name,omitempty"`
+	Match               modifierMatch   `json:"match"`
+	JSONPatch           json.RawMessage `json:"jsonPatch,omitempty"`
+	MergePatch          json.RawMessage `json:"mergePatch,omitempty"`
+	StrategicMergePatch json.RawMessage `json:"strategicMergePatch,omitempty"`
+
+	jsonPatch jsonpatch.Patch
+	name      *regexp.Regexp
+	selector  labels.Selector
+	matched   int32
+}
+
+// modifierMatch selects objects by every field that is set.
+type modifierMatch struct {
+	// Kinds lists kinds, optionally qualified by their group, e.g. Deployment or Deployment.apps.
+	Kinds []string `json:"kinds,omitempty"`
+	// Namespaces lists glob patterns matched against the namespace the object is restored into. Cluster-scoped
+	// objects never match them.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Name is a regular expression that must match the whole name.
+	Name          string                `json:"name,omitempty"`
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+// modifierResult reports how many objects a rule matched.
+type modifierResult struct {
+	Rule    string `json:"rule"`
+	Matched int32  `json:"matched"`
+}
+
+// loadResourceModifiers reads the rules the restore references, if any. They are checked in full up front, so a
+// broken rule fails the restore before anything is applied.
+func loadResourceModifiers(ctx context.Context, c client.Client, restore *restoreObject) (*resourceModifiers, error) {
+	ref := restore.spec.ResourceModifierRef
+	if ref == nil {
+		return nil, nil
+	}
+	namespace := restore.namespace
+	if namespace == "" {
+		namespace = operatorNamespace()
+	}
+	configMap := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, configMap); err != nil {
+		return nil, fmt.Errorf("resource modifiers: %w", err)
+	}
+	data, ok := configMap.Data[resourceModifiersKey]
+	if !ok {
+		return nil, fmt.Errorf("resource modifiers: ConfigMap %s/%s has no %s key", namespace, ref.Name, resourceModifiersKey)
+	}
+	modifiers := &resourceModifiers{}
+	if err := sigsyaml.UnmarshalStrict([]byte(data), modifiers); err != nil {
+		return nil, fmt.Errorf("resource modifiers: decode ConfigMap %s/%s: %w", namespace, ref.Name, err)
+	}
+	for i := range modifiers.Rules {
+		rule := &modifiers.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("resource modifier %s: %w", rule.Name, err)
+		}
+	}
+	return modifiers, nil
+}
+
+func (r *modifierRule) compile() error {
+	patches := 0
+	for _, patch := range []json.RawMessage{r.JSONPatch, r.MergePatch, r.StrategicMergePatch} {
+		if len(patch) > 0 {
+			patches++
+		}
+	}
+	if patches != 1 {
+		return errors.New("exactly one of jsonPatch, mergePatch and strategicMergePatch is required")
+	}
+	if len(r.JSONPatch) > 0 {
+		patch, err := jsonpatch.DecodePatch(r.JSONPatch)
+		if err != nil {
+			return fmt.Errorf("jsonPatch: %w", err)
+		}
+		r.jsonPatch = patch
+	}
+	for _, pattern := range r.Match.Namespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("match.namespaces: %q: %w", pattern, err)
+		}
+	}
+	if r.Match.Name != "" {
+		name, err := regexp.Compile("^(?:" + r.Match.Name + ")$")
+		if err != nil {
+			return fmt.Errorf("match.name: %w", err)
+		}
+		r.name = name
+	}
+	if r.Match.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(r.Match.LabelSelector)
+		if err != nil {
+			return fmt.Errorf("match.labelSelector: %w", err)
+		}
+		r.selector = selector
+	}
+	return nil
+}
+
+// apply patches obj with every rule that matches it.
+func (m *resourceModifiers) apply(obj *unstructured.Unstructured) error {
+	if m == nil {
+		return nil
+	}
+	for i := range m.Rules {
+		rule := &m.Rules[i]
+		if !rule.matches(obj) {
+			continue
+		}
+		rule.matched++
+		if err := rule.patch(obj); err != nil {
+			return fmt.Errorf("resource modifier %s: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// results reports the match count of each rule, in order.
+func (m *resourceModifiers) results() []modifierResult {
+	if m == nil {
+		return nil
+	}
+	results := make([]modifierResult, 0, len(m.Rules))
+	for _, rule := range m.Rules {
+		results = append(results, modifierResult{Rule: rule.Name, Matched: rule.matched})
+	}
+	return results
+}
+
+func (r *modifierRule) matches(obj *unstructured.Unstructured) bool {
+	if len(r.Match.Kinds) > 0 && !matchesKind(r.Match.Kinds, obj.GroupVersionKind().GroupKind()) {
+		return false
+	}
+	if len(r.Match.Namespaces) > 0 && !matchesNamespace(r.Match.Namespaces, obj.GetNamespace()) {
+		return false
+	}
+	if r.name != nil && !r.name.MatchString(obj.GetName()) {
+		return false
+	}
+	if r.selector != nil && !r.selector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	return true
+}
+
+func matchesKind(kinds []string, gk schema.GroupKind) bool {
+	for _, pattern := range kinds {
+		kind, group, qualified := strings.Cut(pattern, ".")
+		if strings.EqualFold(kind, gk.Kind) && (!qualified || group == gk.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesNamespace(patterns []string, namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *modifierRule) patch(obj *unstructured.Unstructured) error {
+	original, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var patched []byte
+	switch {
+	case r.jsonPatch != nil:
+		patched, err = r.jsonPatch.Apply(original)
+	case len(r.MergePatch) > 0:
+		patched, err = jsonpatch.MergePatch(original, r.MergePatch)
+	default:
+		patched, err = strategicMergePatch(original, r.StrategicMergePatch, obj.GroupVersionKind())
+	}
+	if err != nil {
+		return err
+	}
+	// Decoding through Unstructured keeps integers as int64, which the dynamic client expects.
+	return obj.UnmarshalJSON(patched)
+}
+
+// strategicMergePatch needs the Go type of the object for its merge keys. Kinds without one, such as custom
+// resources, get a JSON merge patch instead.
+func strategicMergePatch(original, patch []byte, gvk schema.GroupVersionKind) ([]byte, error) {
+	typed, err := clientgoscheme.Scheme.New(gvk)
+	if err != nil {
+		return jsonpatch.MergePatch(original, patch)
+	}
+	return strategicpatch.StrategicMergePatch(original, patch, typed)
+}
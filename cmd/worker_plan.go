@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// restorePlanInfix starts the suffix of every plan stored next to an artifact. The rest of the suffix names the
+// restore that made it, so plans of different restores do not overwrite each other.
+const restorePlanInfix = ".plan."
+
+type planAction string
+
+const (
+	planCreate   planAction = "create"
+	planUpdate   planAction = "update"
+	planSkip     planAction = "skip"
+	planConflict planAction = "conflict"
+)
+
+// planItem is the planned action for one object of a dry-run restore.
+type planItem struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	// SourceNamespace is the namespace in the artifact, Namespace the one the object is restored into.
+	SourceNamespace string     `json:"sourceNamespace,omitempty"`
+	Namespace       string     `json:"namespace,omitempty"`
+	Action          planAction `json:"action"`
+	Reason          string     `json:"reason,omitempty"`
+	// Diff is a unified diff of the object's YAML from live state to the state after the restore.
+	Diff string `json:"diff,omitempty"`
+}
+
+// restorePlan is the document a dry-run restore stores next to the artifact.
+type restorePlan struct {
+	Restore  string                            `json:"restore"`
+	Artifact string                            `json:"artifact"`
+	Summary  backupv1alpha1.RestorePlanSummary `json:"summary"`
+	Items    []planItem                        `json:"items"`
+	// Modifiers reports how many objects each resource modifier rule matched.
+	Modifiers []modifierResult `json:"modifiers,omitempty"`
+	// keys prevents listing a namespace twice when it is both created for its objects and restored itself.
+	keys sets.Set[string]
+}
+
+func newRestorePlan() *restorePlan {
+	return &restorePlan{Items: []planItem{}, keys: sets.New[string]()}
+}
+
+func (p *restorePlan) add(item planItem) {
+	key := strings.Join([]string{item.APIVersion, item.Kind, item.Namespace, item.Name}, "/")
+	if p.keys.Has(key) {
+		return
+	}
+	p.keys.Insert(key)
+	p.Items = append(p.Items, item)
+	switch item.Action {
+	case planCreate:
+		p.Summary.Create++
+	case planUpdate:
+		p.Summary.Update++
+	case planSkip:
+		p.Summary.Skip++
+	case planConflict:
+		p.Summary.Conflict++
+	}
+}
+
+func newPlanItem(obj *unstructured.Unstructured, sourceNamespace string) planItem {
+	return planItem{
+		APIVersion:      obj.GetAPIVersion(),
+		Kind:            obj.GetKind(),
+		Name:            obj.GetName(),
+		SourceNamespace: sourceNamespace,
+		Namespace:       obj.GetNamespace(),
+	}
+}
+
+// planObject works out what applyObject would do with obj, using server-side dry runs so admission, defaulting
+// and field ownership are taken into account without changing anything. Objects in a namespace the restore would
+// create cannot be dry-run and are planned from the artifact alone.
+func planObject(ctx context.Context, resourceClient dynamic.ResourceInterface, obj *unstructured.Unstructured, policy backupv1alpha1.RestoreOverwritePolicy, item planItem, namespaceMissing bool) (planItem, error) {
+	if namespaceMissing {
+		item.Action = planCreate
+		item.Reason = "namespace does not exist yet, so the object was not validated by the API server"
+		diff, err := objectDiff(nil, obj)
+		item.Diff = diff
+		return item, err
+	}
+
+	dryRun := []string{metav1.DryRunAll}
+	existing, err := resourceClient.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		created, err := resourceClient.Create(ctx, obj, metav1.CreateOptions{FieldManager: restoreFieldManager, DryRun: dryRun})
+		if err != nil {
+			item.Action = planConflict
+			item.Reason = err.Error()
+			return item, nil
+		}
+		item.Action = planCreate
+		diff, err := objectDiff(nil, created)
+		item.Diff = diff
+		return item, err
+	}
+	if err != nil {
+		return item, err
+	}
+
+	var result *unstructured.Unstructured
+	switch {
+	case policy == backupv1alpha1.RestoreOverwriteSkip:
+		item.Action = planSkip
+		item.Reason = "object exists and overwritePolicy is Skip"
+		return item, nil
+	case policy == backupv1alpha1.RestoreOverwriteReplace && replaceAllowed(obj.GetKind()):
+		// The replacement is previewed as an update, which defaults it the way creating it would.
+		desired := obj.DeepCopy()
+		desired.SetResourceVersion(existing.GetResourceVersion())
+		result, err = resourceClient.Update(ctx, desired, metav1.UpdateOptions{FieldManager: restoreFieldManager, DryRun: dryRun})
+		if errors.IsInvalid(err) {
+			// Immutable fields only stop an update; the replacement deletes the object first.
+			result, err = obj, nil
+		}
+		if err != nil {
+			item.Action = planConflict
+			item.Reason = err.Error()
+			return item, nil
+		}
+		item.Reason = "object is deleted and created again"
+	default:
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			return item, err
+		}
+		// The restore forces the apply; trying without force first finds the fields it would take over.
+		_, err = resourceClient.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: restoreFieldManager, DryRun: dryRun})
+		if errors.IsConflict(err) {
+			item.Action = planConflict
+			item.Reason = "fields owned by other managers would be overwritten: " + err.Error()
+		}
+		force := true
+		result, err = resourceClient.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: restoreFieldManager,
+			Force:        &force,
+			DryRun:       dryRun,
+		})
+		if err != nil {
+			item.Action = planConflict
+			item.Reason = err.Error()
+			return item, nil
+		}
+	}
+
+	if item.Diff, err = objectDiff(existing, result); err != nil {
+		return item, err
+	}
+	switch {
+	case item.Action == planConflict:
+	case item.Diff == "":
+		item.Action = planSkip
+		item.Reason = "object is unchanged"
+	default:
+		item.Action = planUpdate
+	}
+	return item, nil
+}
+
+// sidecarSuffix names a file a restore stores next to the artifact after the restore, e.g.
+// <artifact>.plan.restore.<namespace>.<name>.json.
+func (r *restoreObject) sidecarSuffix(infix string) string {
+	return infix + strings.ToLower(r.kind) + "." + strings.Trim(r.namespace+"."+r.name, ".") + ".json"
+}
+
+// completePlan stores the plan of a dry-run restore next to the artifact and completes the restore with its summary.
+// A read-only location cannot take the plan, so the restore is completed with the summary alone.
+func (r *restoreObject) completePlan(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation string, plan *restorePlan) error {
+	plan.Restore = path.Join(r.kind, r.namespace, r.name)
+	plan.Artifact = artifactLocation
+	summary := plan.Summary
+	stored := fmt.Sprintf("plan not stored: storage location %s is read-only", location.Name)
+	if !storage.ReadOnly(location) {
+		suffix := r.sidecarSuffix(restorePlanInfix)
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err == nil {
+			err = storeArtifactSidecar(ctx, c, location, artifactLocation, suffix, data)
+		}
+		if err != nil {
+			return r.fail(backupv1alpha1.RestoreConditionPlanned, fmt.Errorf("store restore plan: %w", err))
+		}
+		summary.Location = artifactLocation + suffix
+		stored = "plan stored at " + summary.Location
+	}
+
+	completed := r.status
+	now := metav1.Now()
+	completed.Plan = &summary
+	completed.Phase = backupv1alpha1.RestorePhaseCompleted
+	completed.CompletedAt = &now
+	completed.Message = fmt.Sprintf("dry run: %d to create, %d to update, %d to skip, %d conflicts; %s",
+		summary.Create, summary.Update, summary.Skip, summary.Conflict, stored)
+	meta.SetStatusCondition(&completed.Conditions, metav1.Condition{
+		Type:               backupv1alpha1.RestoreConditionPlanned,
+		Status:             metav1.ConditionTrue,
+		Reason:             backupv1alpha1.ConditionReasonSucceeded,
+		Message:            completed.Message,
+		ObservedGeneration: r.generation,
+	})
+	return r.update(completed)
+}
+
+// objectDiff renders a unified diff between two versions of an object, leaving out the fields the server manages.
+// A nil object stands for one that does not exist.
+func objectDiff(from, to *unstructured.Unstructured) (string, error) {
+	fromYAML, err := diffableYAML(from)
+	if err != nil {
+		return "", err
+	}
+	toYAML, err := diffableYAML(to)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff("live", "restored", fromYAML, toYAML), nil
+}
+
+func diffableYAML(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	obj = obj.DeepCopy()
+	for _, field := range []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	data, err := sigsyaml.Marshal(obj.Object)
+	return string(data), err
+}
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+	// maxDiffCells bounds the line-by-line comparison; larger objects are shown as replaced outright.
+	maxDiffCells = 1 << 22
+)
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff returns the changes from one text to the other in unified diff format, or "" when they are equal.
+func unifiedDiff(fromName, toName, from, to string) string {
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	var changes []int
+	for i, line := range lines {
+		if line.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	// fromPos and toPos hold the number of lines of each side before lines[i].
+	fromPos := make([]int, len(lines)+1)
+	toPos := make([]int, len(lines)+1)
+	for i, line := range lines {
+		fromPos[i+1], toPos[i+1] = fromPos[i], toPos[i]
+		if line.op != '+' {
+			fromPos[i+1]++
+		}
+		if line.op != '-' {
+			toPos[i+1]++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for k := 0; k < len(changes); {
+		first, last := changes[k], changes[k]
+		// Changes whose context would touch or overlap share a hunk, as in diff -u.
+		for k++; k < len(changes) && changes[k]-last <= 2*diffContext+1; k++ {
+			last = changes[k]
+		}
+		start, end := max(0, first-diffContext), min(len(lines), last+diffContext+1)
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(fromPos[start], fromPos[end]-fromPos[start]),
+			hunkRange(toPos[start], toPos[end]-toPos[start]))
+		for _, line := range lines[start:end] {
+			out.WriteByte(line.op)
+			out.WriteString(line.text)
+			out.WriteByte('\n')
+		}
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines aligns two texts on their longest common subsequence of lines.
+func diffLines(from, to []string) []diffLine {
+	lines := make([]diffLine, 0, len(from)+len(to))
+	if len(from)*len(to) > maxDiffCells {
+		for _, text := range from {
+			lines = append(lines, diffLine{'-', text})
+		}
+		for _, text := range to {
+			lines = append(lines, diffLine{'+', text})
+		}
+		return lines
+	}
+
+	// common[i*width+j] is the length of the longest common subsequence of from[i:] and to[j:].
+	width := len(to) + 1
+	common := make([]int32, (len(from)+1)*width)
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				common[i*width+j] = common[(i+1)*width+j+1] + 1
+			} else {
+				common[i*width+j] = max(common[(i+1)*width+j], common[i*width+j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			lines = append(lines, diffLine{' ', from[i]})
+			i++
+			j++
+		case common[(i+1)*width+j] >= common[i*width+j+1]:
+			lines = append(lines, diffLine{'-', from[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		lines = append(lines, diffLine{'-', from[i]})
+	}
+	for ; j < len(to); j++ {
+		lines = append(lines, diffLine{'+', to[j]})
+	}
+	return lines
+}
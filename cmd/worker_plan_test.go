@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns lines l1 to ln, with the lines in replace swapped for the given text.
+func numberedLines(n int, replace map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if text, ok := replace[i]; ok {
+			b.WriteString(text + "\n")
+		} else {
+			fmt.Fprintf(&b, "l%d\n", i)
+		}
+	}
+	return b.String()
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		from, to string
+		hunks    []string
+	}{
+		{name: "equal", from: "a\nb\n", to: "a\nb\n"},
+		{name: "empty from", from: "", to: "a\nb\n", hunks: []string{"@@ -0,0 +1,2 @@"}},
+		{name: "empty to", from: "a\nb\n", to: "", hunks: []string{"@@ -1,2 +0,0 @@"}},
+		{name: "single line", from: "a\n", to: "b\n", hunks: []string{"@@ -1 +1 @@"}},
+		{name: "missing final newline", from: "a\nb", to: "a\nc\n", hunks: []string{"@@ -1,2 +1,2 @@"}},
+		{
+			name:  "five unchanged lines between changes share a hunk",
+			from:  numberedLines(20, nil),
+			to:    numberedLines(20, map[int]string{5: "x", 11: "y"}),
+			hunks: []string{"@@ -2,13 +2,13 @@"},
+		},
+		{
+			name:  "six unchanged lines between changes share a hunk",
+			from:  numberedLines(20, nil),
+			to:    numberedLines(20, map[int]string{4: "x", 11: "y"}),
+			hunks: []string{"@@ -1,14 +1,14 @@"},
+		},
+		{
+			name:  "seven unchanged lines between changes split the hunk",
+			from:  numberedLines(20, nil),
+			to:    numberedLines(20, map[int]string{4: "x", 12: "y"}),
+			hunks: []string{"@@ -1,7 +1,7 @@", "@@ -9,7 +9,7 @@"},
+		},
+		{
+			name:  "hunks clipped at both ends",
+			from:  numberedLines(20, nil),
+			to:    numberedLines(20, map[int]string{3: "x", 17: "y"}),
+			hunks: []string{"@@ -1,6 +1,6 @@", "@@ -14,7 +14,7 @@"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := unifiedDiff("live", "restored", tc.from, tc.to)
+			if tc.hunks == nil {
+				if diff != "" {
+					t.Fatalf("diff = %q, want none", diff)
+				}
+				return
+			}
+			if !strings.HasPrefix(diff, "--- live\n+++ restored\n") {
+				t.Fatalf("diff = %q, want it to start with the file headers", diff)
+			}
+			var hunks []string
+			for _, line := range strings.Split(diff, "\n") {
+				if strings.HasPrefix(line, "@@") {
+					hunks = append(hunks, line)
+				}
+			}
+			if !reflect.DeepEqual(hunks, tc.hunks) {
+				t.Errorf("hunk headers = %q, want %q\n%s", hunks, tc.hunks, diff)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffLines(t *testing.T) {
+	from := numberedLines(10, nil)
+	to := numberedLines(10, map[int]string{5: "x"})
+	want := "--- live\n+++ restored\n@@ -2,7 +2,7 @@\n l2\n l3\n l4\n-l5\n+x\n l6\n l7\n l8\n"
+	if diff := unifiedDiff("live", "restored", from, to); diff != want {
+		t.Errorf("diff = %q, want %q", diff, want)
+	}
+}
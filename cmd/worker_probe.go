@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runProbeWorker validates a storage location that only worker Jobs can reach, such as an NFS export, by writing,
+// reading back and deleting a sentinel object through the Job's mount, or only listing it when the export is
+// read-only. The outcome is recorded in the location's status.
+func runProbeWorker(ctx context.Context, c client.Client, cfg workerConfig) error {
+	if cfg.kind != "BackupStorageLocation" {
+		return fmt.Errorf("probe-worker does not support kind %q", cfg.kind)
+	}
+	location := &backupv1alpha1.BackupStorageLocation{}
+	if err := c.Get(ctx, client.ObjectKey{Name: cfg.name}, location); err != nil {
+		return err
+	}
+
+	backend, err := storage.New(ctx, c, location)
+	var latency time.Duration
+	if err == nil {
+		latency, err = storage.Probe(ctx, location, backend)
+	}
+	updateErr := storage.PatchStatus(ctx, c, location, func(location *backupv1alpha1.BackupStorageLocation) {
+		storage.SetAvailability(location, backupv1alpha1.ConditionReasonFailed, latency, err)
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+	return err
+}
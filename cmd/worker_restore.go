@@ -2,23 +2,27 @@ package main
 
 import (
 	"archive/tar"
-	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
-	"sort"
+	"path"
+	"strings"
+	"time"
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
 	"example.com/backup-operator/internal/resolve"
+	"example.com/backup-operator/internal/storage"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/discovery/cached/memory"
@@ -26,16 +30,18 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type restoreObject struct {
-	kind      string
-	name      string
-	namespace string
-	spec      backupv1alpha1.RestoreSpec
-	status    backupv1alpha1.RestoreStatus
-	update    func(status backupv1alpha1.RestoreStatus) error
+	kind       string
+	name       string
+	namespace  string
+	generation int64
+	spec       backupv1alpha1.RestoreSpec
+	status     backupv1alpha1.RestoreStatus
+	update     func(status backupv1alpha1.RestoreStatus) error
 }
 
 type backupRef struct {
@@ -69,62 +75,231 @@ func runRestoreWorker(ctx context.Context, c client.Client, restCfg *rest.Config
 		return restore.update(failedRestoreStatus(restore.status, err.Error()))
 	}
 
-	workDir, err := os.MkdirTemp("", "restore-worker-")
-	if err != nil {
-		return err
+	targetCfg := restCfg
+	if restore.spec.TargetClusterRef != nil {
+		remoteCfg, err := buildRemoteConfigForRestore(ctx, c, restore.spec.TargetClusterRef.Name)
+		if err != nil {
+			return restore.update(failedRestoreStatus(restore.status, err.Error()))
+		}
+		targetCfg = remoteCfg
 	}
-	defer os.RemoveAll(workDir)
 
-	artifactPath := filepath.Join(workDir, artifactFileName)
-	if err := loadArtifact(ctx, c, storage, source.status.ArtifactLocation, artifactPath); err != nil {
-		return restore.update(failedRestoreStatus(restore.status, err.Error()))
+	defaultNamespace := ""
+	if restore.kind == "Restore" {
+		defaultNamespace = restore.namespace
 	}
 
-	files, err := extractTarGz(artifactPath)
+	modifiers, err := loadResourceModifiers(ctx, c, restore)
 	if err != nil {
-		return restore.update(failedRestoreStatus(restore.status, err.Error()))
+		return restore.fail(backupv1alpha1.RestoreConditionResourcesApplied, err)
+	}
+
+	applier, err := newResourceApplier(ctx, targetCfg, applyOptions{
+		namespaceMapping: restore.spec.NamespaceMapping,
+		defaultNamespace: defaultNamespace,
+		overwritePolicy:  restore.spec.OverwritePolicy,
+		dryRun:           restore.spec.DryRun,
+		errorPolicy:      restore.spec.ErrorPolicy,
+		modifiers:        modifiers,
+	})
+	if err != nil {
+		return restore.fail(backupv1alpha1.RestoreConditionResourcesApplied, err)
 	}
 
-	resourceObjects, err := decodeYAMLDocuments(files["resources.yaml"])
+	// The artifact is checked in full before anything is applied, because a corrupted tail would otherwise only be
+	// noticed after part of it had been restored.
+	report, err := verifyArtifactIntegrity(ctx, c, storage, source.spec, source.status)
+	// Entries are only checked again while applying when the artifact passed verification.
+	var verifier *entryVerifier
+	if err == nil {
+		verifier = newEntryVerifier(report)
+	}
+	switch {
+	case err != nil && isIntegrityError(err) && restore.spec.IgnoreIntegrityErrors && signatureRequired(storage):
+		// The signature only covers the manifest, so it says nothing about entries that do not match it.
+		return restore.fail(backupv1alpha1.RestoreConditionArtifactVerified, fmt.Errorf(
+			"%w; ignoreIntegrityErrors does not apply to locations that require signed artifacts", err))
+	case err != nil && isIntegrityError(err) && restore.spec.IgnoreIntegrityErrors:
+		err = restore.setCondition(backupv1alpha1.RestoreConditionArtifactVerified, metav1.ConditionFalse,
+			backupv1alpha1.ConditionReasonFailed, fmt.Sprintf("%s; restoring anyway because ignoreIntegrityErrors is set", err))
+	case err != nil && isIntegrityError(err):
+		return restore.fail(backupv1alpha1.RestoreConditionArtifactVerified, err)
+	case err != nil:
+		return restore.fail(backupv1alpha1.RestoreConditionArtifactDownloaded, err)
+	case report.verified():
+		err = restore.setCondition(backupv1alpha1.RestoreConditionArtifactVerified, metav1.ConditionTrue,
+			backupv1alpha1.ConditionReasonSucceeded, report.message())
+	default:
+		err = restore.setCondition(backupv1alpha1.RestoreConditionArtifactVerified, metav1.ConditionTrue,
+			backupv1alpha1.ConditionReasonSkipped, report.message())
+	}
 	if err != nil {
-		return restore.update(failedRestoreStatus(restore.status, err.Error()))
+		return err
 	}
 
-	if len(files["snapshots.yaml"]) > 0 {
-		snapshotObjects, err := decodeYAMLDocuments(files["snapshots.yaml"])
+	// ignoreIntegrityErrors does not extend to signatures: an untrusted artifact is never restored.
+	if signatureRequired(storage) {
+		var manifest []byte
+		if report != nil {
+			manifest = report.manifest
+		}
+		signer, err := verifyArtifactSignature(ctx, c, storage, source.status.ArtifactLocation, manifest)
 		if err != nil {
-			return restore.update(failedRestoreStatus(restore.status, err.Error()))
+			return restore.fail(backupv1alpha1.RestoreConditionSignatureVerified, err)
 		}
-		resourceObjects = append(resourceObjects, snapshotObjects...)
+		err = restore.setCondition(backupv1alpha1.RestoreConditionSignatureVerified, metav1.ConditionTrue,
+			backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("manifest signed by trusted key %s", signer))
+		if err != nil {
+			return err
+		}
+	} else if err := restore.setCondition(backupv1alpha1.RestoreConditionSignatureVerified, metav1.ConditionTrue,
+		backupv1alpha1.ConditionReasonSkipped, fmt.Sprintf("BackupStorageLocation %s has no trusted public keys", storage.Name)); err != nil {
+		return err
 	}
 
-	targetCfg := restCfg
-	if restore.spec.TargetClusterRef != nil {
-		remoteCfg, err := buildRemoteConfigForRestore(ctx, c, restore.spec.TargetClusterRef.Name)
+	artifact, err := openBackupArtifact(ctx, c, storage, source.spec, source.status.ArtifactLocation)
+	if err != nil {
+		return restore.fail(backupv1alpha1.RestoreConditionArtifactDownloaded, err)
+	}
+	defer artifact.Close()
+
+	// Objects are applied while the archive is still being read, so only one of them is held in memory.
+	// applyErr separates failures to apply from failures to read.
+	var applyErr error
+	var snapshotObjects []*unstructured.Unstructured
+	err = readArtifact(artifact, func(name string, entry io.Reader) error {
+		entry, err := verifier.verify(name, entry)
 		if err != nil {
-			return restore.update(failedRestoreStatus(restore.status, err.Error()))
+			return err
 		}
-		targetCfg = remoteCfg
+		switch {
+		case name == "snapshots.yaml":
+			// VolumeSnapshots go last, after the objects they may depend on.
+			objs, err := decodeYAMLDocuments(entry)
+			snapshotObjects = objs
+			return err
+		case name == "resources.yaml" || strings.HasPrefix(name, resourcesEntryDir+"/"):
+			return decodeObjects(entry, func(obj *unstructured.Unstructured) error {
+				applyErr = applier.apply(obj)
+				return applyErr
+			})
+		default:
+			return nil
+		}
+	})
+	if err == nil {
+		err = verifier.complete()
 	}
-
-	defaultNamespace := ""
-	if restore.kind == "Restore" {
-		defaultNamespace = restore.namespace
+	if err == nil {
+		for _, obj := range snapshotObjects {
+			if applyErr = applier.apply(obj); applyErr != nil {
+				break
+			}
+		}
+	}
+	result := applier.result
+	if !restore.spec.DryRun {
+		restore.status.Resources = &result.counts
+		// The results only add detail to the counts, so failing to store them does not fail the restore.
+		results := restoreResults{Objects: result.results, Modifiers: modifiers.results()}
+		if err := restore.storeResults(ctx, c, storage, source.status.ArtifactLocation, results); err != nil {
+			ctrl.Log.WithName("restore").Error(err, "unable to store restore results")
+		}
+	}
+	if applyErr != nil && restore.spec.DryRun {
+		return restore.fail(backupv1alpha1.RestoreConditionPlanned, applyErr)
+	}
+	if applyErr != nil {
+		return restore.fail(backupv1alpha1.RestoreConditionResourcesApplied, applyErr)
+	}
+	if err != nil && isIntegrityError(err) {
+		return restore.fail(backupv1alpha1.RestoreConditionArtifactVerified, err)
+	}
+	if err != nil {
+		return restore.fail(backupv1alpha1.RestoreConditionArtifactDownloaded, err)
+	}
+	if err := restore.setCondition(backupv1alpha1.RestoreConditionArtifactDownloaded, metav1.ConditionTrue,
+		backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("downloaded %s", source.status.ArtifactLocation)); err != nil {
+		return err
+	}
+	if restore.spec.DryRun {
+		result.plan.Modifiers = modifiers.results()
+		return restore.completePlan(ctx, c, storage, source.status.ArtifactLocation, result.plan)
+	}
+	if result.counts.Failed > 0 || result.counts.Unmapped > 0 {
+		message := fmt.Sprintf("applied %d resources, %d failed, %d unmapped", result.applied, result.counts.Failed,
+			result.counts.Unmapped)
+		if restore.status.ResultsLocation != "" {
+			message += "; see " + restore.status.ResultsLocation
+		}
+		err = restore.setCondition(backupv1alpha1.RestoreConditionResourcesApplied, metav1.ConditionTrue,
+			backupv1alpha1.ConditionReasonPartiallyFailed, message)
+	} else {
+		err = restore.setCondition(backupv1alpha1.RestoreConditionResourcesApplied, metav1.ConditionTrue,
+			backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("applied %d resources", result.applied))
+	}
+	if err != nil {
+		return err
 	}
 
-	if err := applyResources(ctx, targetCfg, resourceObjects, restore.spec.NamespaceMapping, defaultNamespace); err != nil {
-		return restore.update(failedRestoreStatus(restore.status, err.Error()))
+	switch {
+	case len(result.workloads) == 0:
+		err = restore.setCondition(backupv1alpha1.RestoreConditionWorkloadsReady, metav1.ConditionTrue,
+			backupv1alpha1.ConditionReasonSkipped, "no workloads restored")
+	case restore.spec.ExecutionMode == backupv1alpha1.ExecutionModeSync:
+		if err := waitForWorkloadsAvailable(ctx, targetCfg, result.workloads); err != nil {
+			return restore.fail(backupv1alpha1.RestoreConditionWorkloadsReady, err)
+		}
+		err = restore.setCondition(backupv1alpha1.RestoreConditionWorkloadsReady, metav1.ConditionTrue,
+			backupv1alpha1.ConditionReasonSucceeded, fmt.Sprintf("%d workloads available", len(result.workloads)))
+	default:
+		err = restore.setCondition(backupv1alpha1.RestoreConditionWorkloadsReady, metav1.ConditionUnknown,
+			backupv1alpha1.ConditionReasonNotAwaited, "workload availability is only awaited in Sync execution mode")
+	}
+	if err != nil {
+		return err
 	}
 
 	completed := restore.status
 	now := metav1.Now()
 	completed.Phase = backupv1alpha1.RestorePhaseCompleted
 	completed.CompletedAt = &now
-	completed.Message = "restore completed"
-	completed.ObservedGeneration = restore.status.ObservedGeneration
+	completed.Message = fmt.Sprintf("restore completed: %d created, %d updated, %d replaced, %d skipped, %d unmapped",
+		result.counts.Created, result.counts.Updated, result.counts.Replaced, result.counts.Skipped, result.counts.Unmapped)
+	if result.counts.Failed > 0 {
+		completed.Phase = backupv1alpha1.RestorePhasePartiallyFailed
+		completed.Message = fmt.Sprintf("restore partially failed: %d created, %d updated, %d replaced, %d skipped, %d unmapped, %d failed",
+			result.counts.Created, result.counts.Updated, result.counts.Replaced, result.counts.Skipped, result.counts.Unmapped,
+			result.counts.Failed)
+	}
 	return restore.update(completed)
 }
 
+// setCondition records the outcome of a restore stage and persists it right away.
+func (r *restoreObject) setCondition(conditionType string, status metav1.ConditionStatus, reason, message string) error {
+	meta.SetStatusCondition(&r.status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: r.generation,
+	})
+	return r.update(r.status)
+}
+
+// fail marks the stage that failed and the restore as Failed.
+func (r *restoreObject) fail(conditionType string, err error) error {
+	failed := failedRestoreStatus(r.status, err.Error())
+	meta.SetStatusCondition(&failed.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             failureReason(err),
+		Message:            err.Error(),
+		ObservedGeneration: r.generation,
+	})
+	return r.update(failed)
+}
+
 func loadRestoreObject(ctx context.Context, c client.Client, cfg workerConfig) (*restoreObject, error) {
 	switch cfg.kind {
 	case "Restore":
@@ -133,11 +308,12 @@ func loadRestoreObject(ctx context.Context, c client.Client, cfg workerConfig) (
 			return nil, err
 		}
 		return &restoreObject{
-			kind:      "Restore",
-			name:      restore.Name,
-			namespace: restore.Namespace,
-			spec:      restore.Spec,
-			status:    restore.Status,
+			kind:       "Restore",
+			name:       restore.Name,
+			namespace:  restore.Namespace,
+			generation: restore.Generation,
+			spec:       restore.Spec,
+			status:     restore.Status,
 			update: func(status backupv1alpha1.RestoreStatus) error {
 				restore.Status = status
 				return c.Status().Update(ctx, &restore)
@@ -149,11 +325,12 @@ func loadRestoreObject(ctx context.Context, c client.Client, cfg workerConfig) (
 			return nil, err
 		}
 		return &restoreObject{
-			kind:      "ClusterRestore",
-			name:      restore.Name,
-			namespace: "",
-			spec:      restore.Spec.RestoreSpec,
-			status:    restore.Status.RestoreStatus,
+			kind:       "ClusterRestore",
+			name:       restore.Name,
+			namespace:  "",
+			generation: restore.Generation,
+			spec:       restore.Spec.RestoreSpec,
+			status:     restore.Status.RestoreStatus,
 			update: func(status backupv1alpha1.RestoreStatus) error {
 				restore.Status.RestoreStatus = status
 				return c.Status().Update(ctx, &restore)
@@ -178,164 +355,660 @@ func loadBackupReference(ctx context.Context, c client.Client, ref backupv1alpha
 			return nil, err
 		}
 		return &backupRef{name: backup.Name, namespace: "", spec: backup.Spec.BackupSpec, status: backup.Status.BackupStatus}, nil
+	case backupv1alpha1.RestoreSourceArtifact:
+		return loadArtifactReference(ctx, c, ref)
 	default:
 		return nil, fmt.Errorf("unsupported sourceRef.kind %q", ref.Kind)
 	}
 }
 
+// loadArtifactReference finds an artifact in storage and describes it with the catalog entry stored next to it, for
+// restores whose source backup does not exist in this cluster.
+func loadArtifactReference(ctx context.Context, c client.Client, ref backupv1alpha1.RestoreSourceRef) (*backupRef, error) {
+	if ref.Artifact == nil {
+		return nil, fmt.Errorf("sourceRef.artifact is required for kind %s", ref.Kind)
+	}
+	location, err := resolve.StorageLocation(ctx, c, ref.Artifact.StorageRef.Name)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := storage.New(ctx, c, location)
+	if err != nil {
+		return nil, err
+	}
+	key, err := findArtifactKey(ctx, backend, ref)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := loadCatalogEntry(ctx, backend, key)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := entry.Spec
+	spec.StorageRef = &corev1.LocalObjectReference{Name: location.Name}
+	status := entry.Status
+	status.ArtifactLocation = backend.Location(key)
+	return &backupRef{name: entry.Name, namespace: entry.Namespace, spec: spec, status: status}, nil
+}
+
+// findArtifactKey returns the key of the artifact given by path, or else the one that storeArtifact wrote for the
+// backup named by ref, picking the latest run unless a timestamp is given.
+func findArtifactKey(ctx context.Context, backend storage.Backend, ref backupv1alpha1.RestoreSourceRef) (string, error) {
+	source := ref.Artifact
+	if source.Path != "" {
+		key := strings.TrimPrefix(source.Path, "/")
+		if strings.Contains(source.Path, "://") {
+			var err error
+			if key, err = backend.Key(source.Path); err != nil {
+				return "", err
+			}
+		}
+		if _, err := backend.Stat(ctx, key); err != nil {
+			return "", fmt.Errorf("artifact %s: %w", backend.Location(key), err)
+		}
+		return key, nil
+	}
+
+	sourceCluster := source.ClusterID
+	if sourceCluster == "" {
+		sourceCluster = clusterID()
+	}
+	kind := "backup"
+	if ref.Namespace == "" {
+		kind = "clusterbackup"
+	}
+	prefix := path.Join(sourceCluster, kind, namespaceSegment(ref.Namespace), ref.Name) + "/"
+	if source.Timestamp != "" {
+		prefix += source.Timestamp + "/"
+	}
+	// Timestamps sort lexically in time order, and List returns keys in lexical order, so the last match is the
+	// latest run.
+	found := ""
+	err := backend.List(ctx, prefix, func(object storage.Object) error {
+		if isArtifactKey(object.Key) {
+			found = object.Key
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if found == "" {
+		return "", fmt.Errorf("no artifact found under %s", backend.Location(prefix))
+	}
+	return found, nil
+}
+
 func failedRestoreStatus(current backupv1alpha1.RestoreStatus, message string) backupv1alpha1.RestoreStatus {
 	now := metav1.Now()
 	current.Phase = backupv1alpha1.RestorePhaseFailed
 	current.Message = message
 	current.CompletedAt = &now
-	current.ObservedGeneration = current.ObservedGeneration
 	return current
 }
 
-func extractTarGz(path string) (map[string][]byte, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	gzipReader, err := gzip.NewReader(file)
+// readArtifact walks the entries of a backup archive in order, handing each one to visit as a stream.
+func readArtifact(r io.Reader, visit func(name string, entry io.Reader) error) error {
+	gzipReader, err := gzip.NewReader(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer gzipReader.Close()
 
 	tarReader := tar.NewReader(gzipReader)
-	files := map[string][]byte{}
 	for {
 		head, err := tarReader.Next()
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
-			return nil, err
-		}
-		if head.Size == 0 {
-			continue
+			return err
 		}
-		buf := make([]byte, head.Size)
-		if _, err := io.ReadFull(tarReader, buf); err != nil {
-			return nil, err
+		if err := visit(head.Name, tarReader); err != nil {
+			return err
 		}
-		files[head.Name] = buf
 	}
-	return files, nil
 }
 
-func decodeYAMLDocuments(data []byte) ([]*unstructured.Unstructured, error) {
-	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
-	var objs []*unstructured.Unstructured
+// decodeObjects decodes YAML or JSON documents from r one at a time.
+func decodeObjects(r io.Reader, visit func(*unstructured.Unstructured) error) error {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
 	for {
 		var raw map[string]any
 		if err := decoder.Decode(&raw); err != nil {
 			if err == io.EOF {
-				break
+				return nil
 			}
-			return nil, err
+			return err
 		}
 		if len(raw) == 0 {
 			continue
 		}
-		objs = append(objs, &unstructured.Unstructured{Object: raw})
+		if err := visit(&unstructured.Unstructured{Object: raw}); err != nil {
+			return err
+		}
 	}
-	return objs, nil
 }
 
-func applyResources(ctx context.Context, restCfg *rest.Config, resources []*unstructured.Unstructured, mapping map[string]string, defaultNamespace string) error {
+func decodeYAMLDocuments(r io.Reader) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	err := decodeObjects(r, func(obj *unstructured.Unstructured) error {
+		objs = append(objs, obj)
+		return nil
+	})
+	return objs, err
+}
+
+// applyOptions controls how a resourceApplier writes objects into the target cluster.
+type applyOptions struct {
+	namespaceMapping map[string]string
+	defaultNamespace string
+	overwritePolicy  backupv1alpha1.RestoreOverwritePolicy
+	// dryRun plans each object instead of writing it.
+	dryRun      bool
+	errorPolicy backupv1alpha1.RestoreErrorPolicy
+	// modifiers patch objects after they are sanitized and mapped to their target namespace.
+	modifiers *resourceModifiers
+}
+
+// applyResult reports what a resourceApplier did.
+type applyResult struct {
+	// applied counts the objects handed to the applier, including skipped kinds.
+	applied int
+	counts  backupv1alpha1.RestoreResourceCounts
+	// workloads lists the Deployments, StatefulSets and DaemonSets that were written.
+	workloads []appliedWorkload
+	// results holds the outcome for each object, in the order they were applied.
+	results []restoreResult
+	// plan is filled in instead of counts and results when dry-running.
+	plan *restorePlan
+}
+
+type appliedWorkload struct {
+	resource  schema.GroupVersionResource
+	kind      string
+	namespace string
+	name      string
+}
+
+// resourceApplier writes restored objects one at a time, in the order they are read from the artifact.
+type resourceApplier struct {
+	ctx    context.Context
+	dyn    dynamic.Interface
+	mapper *restmapper.DeferredDiscoveryRESTMapper
+	opts   applyOptions
+	result *applyResult
+	// namespaces remembers target namespaces that are known to exist.
+	namespaces sets.Set[string]
+	// missingNamespaces remembers target namespaces a dry run would have created.
+	missingNamespaces sets.Set[string]
+	// mapperStale is set once a restored CRD or APIService may serve types the mapper has not discovered yet.
+	mapperStale bool
+	// apiServices maps the group versions of restored APIServices to their names until their types are needed.
+	apiServices map[schema.GroupVersion]string
+	// plannedKinds remembers the kinds of the CRDs a dry run would have created.
+	plannedKinds sets.Set[schema.GroupKind]
+}
+
+func newResourceApplier(ctx context.Context, restCfg *rest.Config, opts applyOptions) (*resourceApplier, error) {
 	dyn, err := dynamic.NewForConfig(restCfg)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	disco, err := discovery.NewDiscoveryClientForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+	return &resourceApplier{
+		ctx:               ctx,
+		dyn:               dyn,
+		mapper:            restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(disco)),
+		opts:              opts,
+		result:            &applyResult{plan: newRestorePlan()},
+		namespaces:        sets.New[string](),
+		missingNamespaces: sets.New[string](),
+		apiServices:       map[schema.GroupVersion]string{},
+		plannedKinds:      sets.New[schema.GroupKind](),
+	}, nil
+}
+
+func (a *resourceApplier) ensureNamespace(namespace string) error {
+	if namespace == "" || a.namespaces.Has(namespace) {
+		return nil
+	}
+	gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
+	_, err := a.dyn.Resource(gvr).Get(a.ctx, namespace, metav1.GetOptions{})
+	if errors.IsNotFound(err) && a.opts.dryRun {
+		a.result.plan.add(planItem{APIVersion: "v1", Kind: "Namespace", Name: namespace, Action: planCreate,
+			Reason: "namespace of restored objects does not exist"})
+		a.missingNamespaces.Insert(namespace)
+		a.namespaces.Insert(namespace)
+		return nil
+	}
+	if errors.IsNotFound(err) {
+		obj := &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata": map[string]any{
+				"name": namespace,
+			},
+		}}
+		_, err = a.dyn.Resource(gvr).Create(a.ctx, obj, metav1.CreateOptions{})
+	}
 	if err != nil {
 		return err
 	}
-	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(disco))
+	a.namespaces.Insert(namespace)
+	return nil
+}
 
-	ensureNamespace := func(namespace string) error {
-		if namespace == "" {
-			return nil
+func (a *resourceApplier) apply(obj *unstructured.Unstructured) error {
+	if obj == nil || obj.Object == nil {
+		return nil
+	}
+	a.result.applied++
+	sourceNamespace := obj.GetNamespace()
+	if shouldSkipKind(obj.GetKind()) {
+		if a.opts.dryRun {
+			item := newPlanItem(obj, sourceNamespace)
+			item.Action, item.Reason = planSkip, "kind is never restored"
+			a.result.plan.add(item)
 		}
-		gvr := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
-		_, err := dyn.Resource(gvr).Get(ctx, namespace, metav1.GetOptions{})
-		if errors.IsNotFound(err) {
-			obj := &unstructured.Unstructured{Object: map[string]any{
-				"apiVersion": "v1",
-				"kind":       "Namespace",
-				"metadata": map[string]any{
-					"name": namespace,
-				},
-			}}
-			_, err = dyn.Resource(gvr).Create(ctx, obj, metav1.CreateOptions{})
+		return nil
+	}
+	sanitizeObject(obj)
+
+	if obj.GetNamespace() != "" {
+		targetNamespace := obj.GetNamespace()
+		if a.opts.defaultNamespace != "" {
+			targetNamespace = a.opts.defaultNamespace
 		}
-		return err
+		if a.opts.namespaceMapping != nil {
+			if mapped, ok := a.opts.namespaceMapping[obj.GetNamespace()]; ok {
+				targetNamespace = mapped
+			}
+		}
+		obj.SetNamespace(targetNamespace)
+	}
+	// Modifiers see the object as it will be restored, and may still change its namespace.
+	if err := a.opts.modifiers.apply(obj); err != nil {
+		err = fmt.Errorf("%s %s: %w", obj.GetKind(), objectKey(obj), err)
+		if a.opts.dryRun {
+			item := newPlanItem(obj, sourceNamespace)
+			item.Action, item.Reason = planConflict, err.Error()
+			a.result.plan.add(item)
+			return nil
+		}
+		return a.fail(obj, err)
+	}
+	if err := a.ensureNamespace(obj.GetNamespace()); err != nil {
+		return a.fail(obj, fmt.Errorf("namespace %s: %w", obj.GetNamespace(), err))
 	}
 
-	sort.SliceStable(resources, func(i, j int) bool {
-		return resourcePriority(resources[i]) < resourcePriority(resources[j])
-	})
+	gvk := obj.GroupVersionKind()
+	mappingInfo, err := a.restMapping(gvk)
+	// Only a kind the cluster does not serve is unmapped; any other error, e.g. from discovery of a broken
+	// aggregated API, is a failure like any other.
+	switch {
+	case meta.IsNoMatchError(err) && a.opts.dryRun:
+		return a.planUnmapped(obj, sourceNamespace)
+	case meta.IsNoMatchError(err):
+		a.result.record(obj, applyUnmapped, err)
+		return nil
+	case err != nil && a.opts.dryRun:
+		return fmt.Errorf("%s %s: %w", gvk.Kind, objectKey(obj), err)
+	case err != nil:
+		return a.fail(obj, fmt.Errorf("%s %s: %w", gvk.Kind, objectKey(obj), err))
+	}
 
-	for _, obj := range resources {
-		if obj == nil || obj.Object == nil {
-			continue
+	var resourceClient dynamic.ResourceInterface = a.dyn.Resource(mappingInfo.Resource)
+	if mappingInfo.Scope.Name() == meta.RESTScopeNameNamespace {
+		resourceClient = a.dyn.Resource(mappingInfo.Resource).Namespace(obj.GetNamespace())
+	}
+
+	obj.SetResourceVersion("")
+	if a.opts.dryRun {
+		item, err := planObject(a.ctx, resourceClient, obj, a.opts.overwritePolicy, newPlanItem(obj, sourceNamespace),
+			a.missingNamespaces.Has(obj.GetNamespace()))
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", gvk.Kind, objectKey(obj), err)
 		}
-		if shouldSkipKind(obj.GetKind()) {
-			continue
+		if item.Action == planCreate && gvk.GroupKind() == crdGroupKind {
+			group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+			kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+			a.plannedKinds.Insert(schema.GroupKind{Group: group, Kind: kind})
 		}
-		sanitizeObject(obj)
+		a.result.plan.add(item)
+		return nil
+	}
+	outcome, err := applyObject(a.ctx, resourceClient, obj, a.opts.overwritePolicy)
+	if err == nil && outcome != applySkipped {
+		err = a.awaitServed(obj)
+	}
+	if err != nil {
+		return a.fail(obj, fmt.Errorf("%s %s: %w", gvk.Kind, objectKey(obj), err))
+	}
+	a.result.record(obj, outcome, nil)
+	if outcome != applySkipped && isWorkloadKind(gvk.Kind) {
+		a.result.workloads = append(a.result.workloads, appliedWorkload{
+			resource:  mappingInfo.Resource,
+			kind:      gvk.Kind,
+			namespace: obj.GetNamespace(),
+			name:      obj.GetName(),
+		})
+	}
+	return nil
+}
 
-		if obj.GetNamespace() != "" {
-			targetNamespace := obj.GetNamespace()
-			if defaultNamespace != "" {
-				targetNamespace = defaultNamespace
-			}
-			if mapping != nil {
-				if mapped, ok := mapping[obj.GetNamespace()]; ok {
-					targetNamespace = mapped
-				}
-			}
-			obj.SetNamespace(targetNamespace)
-			if err := ensureNamespace(targetNamespace); err != nil {
-				return err
-			}
+var (
+	crdGroupKind        = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+	apiServiceGroupKind = schema.GroupKind{Group: "apiregistration.k8s.io", Kind: "APIService"}
+	crdResource         = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	apiServiceResource  = schema.GroupVersionResource{Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices"}
+)
+
+const (
+	// crdEstablishedTimeout bounds the wait for a restored CRD to be served.
+	crdEstablishedTimeout = time.Minute
+	// apiServiceAvailableTimeout bounds the wait for a restored aggregated API. Its backing workload is restored
+	// before the APIService, but may still be starting when the first of its resources is restored.
+	apiServiceAvailableTimeout = 2 * time.Minute
+)
+
+// restMapping maps gvk to its resource. The mapper caches discovery, so types served by CRDs and APIServices
+// restored earlier are only found after it is reset, and aggregated types only once their APIService is available.
+func (a *resourceApplier) restMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err == nil || !meta.IsNoMatchError(err) {
+		return mapping, err
+	}
+	if name, ok := a.apiServices[gvk.GroupVersion()]; ok {
+		// Waited for once; if it never becomes available, restoring its resources fails.
+		delete(a.apiServices, gvk.GroupVersion())
+		if err := waitForCondition(a.ctx, a.dyn.Resource(apiServiceResource), name, "Available", apiServiceAvailableTimeout); err != nil {
+			return nil, fmt.Errorf("APIService %s: %w", name, err)
 		}
+		a.mapperStale = true
+	}
+	if !a.mapperStale {
+		return nil, err
+	}
+	a.mapper.Reset()
+	a.mapperStale = false
+	return a.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
 
-		gvk := obj.GroupVersionKind()
-		mappingInfo, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+// awaitServed handles restored objects that add types to the API. A CRD is waited for until it is established, so
+// its custom resources can follow right away. An APIService is only waited for when its first resource is restored,
+// because the workload that backs it may take a while to start.
+func (a *resourceApplier) awaitServed(obj *unstructured.Unstructured) error {
+	switch obj.GroupVersionKind().GroupKind() {
+	case crdGroupKind:
+		if err := waitForCondition(a.ctx, a.dyn.Resource(crdResource), obj.GetName(), "Established", crdEstablishedTimeout); err != nil {
+			return err
+		}
+		a.mapperStale = true
+	case apiServiceGroupKind:
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		version, _, _ := unstructured.NestedString(obj.Object, "spec", "version")
+		a.apiServices[schema.GroupVersion{Group: group, Version: version}] = obj.GetName()
+		a.mapperStale = true
+	}
+	return nil
+}
+
+// planUnmapped plans an object whose type the target cluster does not serve. Custom resources of a CRD the dry run
+// would have created are planned from the artifact alone.
+func (a *resourceApplier) planUnmapped(obj *unstructured.Unstructured, sourceNamespace string) error {
+	item := newPlanItem(obj, sourceNamespace)
+	if !a.plannedKinds.Has(obj.GroupVersionKind().GroupKind()) {
+		item.Action, item.Reason = planSkip, "resource type is not served by the target cluster"
+		a.result.plan.add(item)
+		return nil
+	}
+	diff, err := objectDiff(nil, obj)
+	if err != nil {
+		return err
+	}
+	item.Action, item.Diff = planCreate, diff
+	item.Reason = "CustomResourceDefinition does not exist yet, so the object was not validated by the API server"
+	a.result.plan.add(item)
+	return nil
+}
+
+// waitForCondition polls a cluster-scoped object until its status condition of the given type is True.
+func waitForCondition(ctx context.Context, resourceClient dynamic.ResourceInterface, name, conditionType string, timeout time.Duration) error {
+	message := ""
+	err := wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		obj, err := resourceClient.Get(ctx, name, metav1.GetOptions{})
 		if err != nil {
-			continue
+			return false, err
+		}
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, raw := range conditions {
+			condition, ok := raw.(map[string]any)
+			if !ok || condition["type"] != conditionType {
+				continue
+			}
+			message, _ = condition["message"].(string)
+			return condition["status"] == string(metav1.ConditionTrue), nil
 		}
+		return false, nil
+	})
+	switch {
+	case err == nil:
+		return nil
+	case message != "":
+		return fmt.Errorf("not %s: %s", strings.ToLower(conditionType), message)
+	default:
+		return fmt.Errorf("not %s: %w", strings.ToLower(conditionType), err)
+	}
+}
+
+// fail records an object that could not be restored. Under the Continue error policy the restore goes on with the
+// next object; otherwise err stops it.
+func (a *resourceApplier) fail(obj *unstructured.Unstructured, err error) error {
+	a.result.record(obj, applyFailed, err)
+	if a.opts.errorPolicy == backupv1alpha1.RestoreErrorPolicyContinue {
+		return nil
+	}
+	return err
+}
+
+const restoreFieldManager = "backup-operator-restore"
 
-		resourceClient := dyn.Resource(mappingInfo.Resource)
-		if mappingInfo.Scope.Name() == meta.RESTScopeNameNamespace {
-			resourceClient = resourceClient.Namespace(obj.GetNamespace())
+type applyOutcome string
+
+const (
+	applyCreated  applyOutcome = "created"
+	applyUpdated  applyOutcome = "updated"
+	applyReplaced applyOutcome = "replaced"
+	applySkipped  applyOutcome = "skipped"
+	// applyFailed and applyUnmapped are only recorded in the results; applyObject never returns them.
+	applyFailed   applyOutcome = "failed"
+	applyUnmapped applyOutcome = "unmapped"
+)
+
+// applyObject writes obj according to the overwrite policy:
+//   - Skip leaves existing objects untouched.
+//   - Merge server-side applies obj so fields owned by other managers survive.
+//   - Replace deletes the existing object and creates obj from scratch, which also
+//     gets past immutable fields. Kinds whose deletion would cascade to data are merged instead.
+func applyObject(ctx context.Context, resourceClient dynamic.ResourceInterface, obj *unstructured.Unstructured, policy backupv1alpha1.RestoreOverwritePolicy) (applyOutcome, error) {
+	existing, err := resourceClient.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		if _, err := resourceClient.Create(ctx, obj, metav1.CreateOptions{FieldManager: restoreFieldManager}); err != nil {
+			return "", err
 		}
+		return applyCreated, nil
+	}
+	if err != nil {
+		return "", err
+	}
 
-		obj.SetResourceVersion("")
-		_, err = resourceClient.Create(ctx, obj, metav1.CreateOptions{})
+	switch {
+	case policy == backupv1alpha1.RestoreOverwriteSkip:
+		return applySkipped, nil
+	case policy == backupv1alpha1.RestoreOverwriteReplace && replaceAllowed(obj.GetKind()):
+		if err := replaceObject(ctx, resourceClient, existing, obj); err != nil {
+			return "", err
+		}
+		return applyReplaced, nil
+	default:
+		data, err := json.Marshal(obj.Object)
 		if err != nil {
-			if errors.IsAlreadyExists(err) {
-				existing, getErr := resourceClient.Get(ctx, obj.GetName(), metav1.GetOptions{})
-				if getErr != nil {
-					return getErr
-				}
-				obj.SetResourceVersion(existing.GetResourceVersion())
-				_, err = resourceClient.Update(ctx, obj, metav1.UpdateOptions{})
-			}
+			return "", err
 		}
+		force := true
+		_, err = resourceClient.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: restoreFieldManager,
+			Force:        &force,
+		})
 		if err != nil {
+			return "", err
+		}
+		return applyUpdated, nil
+	}
+}
+
+// maxReplaceAttempts bounds how often a replace starts over because another client recreated the object between
+// the delete and the create.
+const maxReplaceAttempts = 3
+
+func replaceObject(ctx context.Context, resourceClient dynamic.ResourceInterface, existing, obj *unstructured.Unstructured) error {
+	for attempt := 1; ; attempt++ {
+		if err := deleteAndWait(ctx, resourceClient, existing); err != nil {
+			return err
+		}
+		_, err := resourceClient.Create(ctx, obj, metav1.CreateOptions{FieldManager: restoreFieldManager})
+		if !errors.IsAlreadyExists(err) || attempt == maxReplaceAttempts {
+			return err
+		}
+		// Someone else recreated the object first; replace theirs.
+		current, err := resourceClient.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		switch {
+		case errors.IsNotFound(err):
+		case err != nil:
 			return err
+		default:
+			existing = current
 		}
 	}
+}
+
+// deleteAndWait deletes existing and waits until it is gone or another object took its name.
+func deleteAndWait(ctx context.Context, resourceClient dynamic.ResourceInterface, existing *unstructured.Unstructured) error {
+	uid := existing.GetUID()
+	propagation := metav1.DeletePropagationBackground
+	err := resourceClient.Delete(ctx, existing.GetName(), metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+		Preconditions:     &metav1.Preconditions{UID: &uid},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
 
+	err = wait.PollUntilContextTimeout(ctx, time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
+		current, err := resourceClient.Get(ctx, existing.GetName(), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		// A new object with the same name means someone else recreated it first.
+		return current.GetUID() != uid, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for deletion: %w", err)
+	}
 	return nil
 }
 
+// replaceAllowed reports whether deleting an existing object of this kind is safe. Deleting a
+// Namespace, CRD or volume would take its contents with it.
+func replaceAllowed(kind string) bool {
+	switch kind {
+	case "Namespace", "CustomResourceDefinition", "PersistentVolume", "PersistentVolumeClaim":
+		return false
+	default:
+		return true
+	}
+}
+
+func objectKey(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+func isWorkloadKind(kind string) bool {
+	switch kind {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		return true
+	default:
+		return false
+	}
+}
+
+func waitForWorkloadsAvailable(ctx context.Context, restCfg *rest.Config, workloads []appliedWorkload) error {
+	dyn, err := dynamic.NewForConfig(restCfg)
+	if err != nil {
+		return err
+	}
+
+	pending := workloads
+	err = wait.PollUntilContextTimeout(ctx, 5*time.Second, workloadsAvailableTimeout, true, func(ctx context.Context) (bool, error) {
+		var remaining []appliedWorkload
+		for _, workload := range pending {
+			current, err := dyn.Resource(workload.resource).Namespace(workload.namespace).Get(ctx, workload.name, metav1.GetOptions{})
+			if err != nil {
+				return false, fmt.Errorf("%s %s/%s: %w", workload.kind, workload.namespace, workload.name, err)
+			}
+			if !workloadAvailable(current) {
+				remaining = append(remaining, workload)
+			}
+		}
+		pending = remaining
+		return len(pending) == 0, nil
+	})
+	if err != nil && wait.Interrupted(err) && ctx.Err() == nil {
+		names := make([]string, 0, len(pending))
+		for _, workload := range pending {
+			names = append(names, fmt.Sprintf("%s %s/%s", workload.kind, workload.namespace, workload.name))
+		}
+		return &stageTimeoutError{waitingFor: "workloads to become available: " + strings.Join(names, ", "), timeout: workloadsAvailableTimeout}
+	}
+	return err
+}
+
+// workloadAvailable mirrors the checks kubectl rollout status uses for each workload kind.
+func workloadAvailable(obj *unstructured.Unstructured) bool {
+	generation := obj.GetGeneration()
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if observed < generation {
+		return false
+	}
+
+	switch obj.GetKind() {
+	case "DaemonSet":
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedNumberScheduled")
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberAvailable")
+		return updated >= desired && available >= desired
+	default:
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		updated, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+		available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+		return updated >= replicas && available >= replicas
+	}
+}
+
 func buildRemoteConfigForRestore(ctx context.Context, c client.Client, remoteName string) (*rest.Config, error) {
 	var remote backupv1alpha1.RemoteCluster
 	if err := c.Get(ctx, client.ObjectKey{Name: remoteName}, &remote); err != nil {
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// restoreResultsInfix starts the suffix of the results a restore stores next to the artifact. Like plans, results
+// are named after the restore that wrote them.
+const restoreResultsInfix = ".restore-results."
+
+// restoreResult is one entry of the restore results: what happened to one object of the artifact.
+type restoreResult struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	Action     applyOutcome `json:"action"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// restoreResults is the document a restore stores next to the artifact.
+type restoreResults struct {
+	Objects []restoreResult `json:"objects"`
+	// Modifiers reports how many objects each resource modifier rule matched.
+	Modifiers []modifierResult `json:"modifiers,omitempty"`
+}
+
+// record counts the outcome for obj and adds it to the results.
+func (r *applyResult) record(obj *unstructured.Unstructured, outcome applyOutcome, err error) {
+	switch outcome {
+	case applyCreated:
+		r.counts.Created++
+	case applyUpdated:
+		r.counts.Updated++
+	case applyReplaced:
+		r.counts.Replaced++
+	case applySkipped:
+		r.counts.Skipped++
+	case applyFailed:
+		r.counts.Failed++
+	case applyUnmapped:
+		r.counts.Unmapped++
+	}
+	entry := restoreResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Action:     outcome,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.results = append(r.results, entry)
+}
+
+// storeResults stores the results next to the artifact and records where in status. Nothing is stored in a
+// read-only location.
+func (r *restoreObject) storeResults(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation string, results restoreResults) error {
+	if storage.ReadOnly(location) {
+		return nil
+	}
+	if results.Objects == nil {
+		results.Objects = []restoreResult{}
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	suffix := r.sidecarSuffix(restoreResultsInfix)
+	if err := storeArtifactSidecar(ctx, c, location, artifactLocation, suffix, data); err != nil {
+		return err
+	}
+	r.status.ResultsLocation = artifactLocation + suffix
+	return nil
+}
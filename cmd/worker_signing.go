@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/signing"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The signature covers manifest.json, which in turn lists the SHA-256 of every other entry, so it vouches for the
+// whole artifact content. It is stored next to the artifact as a JSON signing.Signature.
+const (
+	signatureFileSuffix = ".sig"
+	signingKeyDataKey   = "private.pem"
+)
+
+// loadSigningKey returns the key that signs artifacts written to storage, or nil when signing is not configured.
+func loadSigningKey(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation) (crypto.Signer, error) {
+	if storage.Spec.Signing == nil || storage.Spec.Signing.SecretRef == nil {
+		return nil, nil
+	}
+	ref := storage.Spec.Signing.SecretRef
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}
+	if key.Namespace == "" {
+		key.Namespace = operatorNamespace()
+	}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("signing secret: %w", err)
+	}
+	data, ok := secret.Data[signingKeyDataKey]
+	if !ok {
+		return nil, fmt.Errorf("signing secret %s/%s has no %s key", key.Namespace, key.Name, signingKeyDataKey)
+	}
+	signer, err := signing.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("signing key in secret %s/%s: %w", key.Namespace, key.Name, err)
+	}
+	return signer, nil
+}
+
+// signArtifact signs the artifact's manifest and stores the detached signature next to the artifact.
+func signArtifact(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, artifactLocation string, signer crypto.Signer, manifest []byte) (*backupv1alpha1.ArtifactSignature, error) {
+	signature, err := signing.Sign(signer, manifest)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(signature)
+	if err != nil {
+		return nil, err
+	}
+	if err := storeArtifactSidecar(ctx, c, storage, artifactLocation, signatureFileSuffix, data); err != nil {
+		return nil, err
+	}
+	return &backupv1alpha1.ArtifactSignature{Algorithm: signature.Algorithm, KeyFingerprint: signature.KeyFingerprint}, nil
+}
+
+// signatureRequired reports whether artifacts read from storage must carry a trusted signature.
+func signatureRequired(storage *backupv1alpha1.BackupStorageLocation) bool {
+	return storage.Spec.Signing != nil && len(storage.Spec.Signing.TrustedPublicKeys) > 0
+}
+
+// verifyArtifactSignature checks the detached signature of an artifact against the storage location's trusted
+// public keys. manifest is the raw manifest.json read from the artifact. It returns the fingerprint of the key
+// that made the signature.
+func verifyArtifactSignature(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, artifactLocation string, manifest []byte) (string, error) {
+	trusted := make([]crypto.PublicKey, 0, len(storage.Spec.Signing.TrustedPublicKeys))
+	for i, pemKey := range storage.Spec.Signing.TrustedPublicKeys {
+		key, err := signing.ParsePublicKey([]byte(pemKey))
+		if err != nil {
+			return "", fmt.Errorf("trusted public key %d of BackupStorageLocation %s: %w", i, storage.Name, err)
+		}
+		trusted = append(trusted, key)
+	}
+
+	data, err := loadArtifactSidecar(ctx, c, storage, artifactLocation, signatureFileSuffix)
+	if err != nil {
+		return "", fmt.Errorf("read artifact signature: %w", err)
+	}
+	if data == nil {
+		return "", fmt.Errorf("artifact is not signed, but BackupStorageLocation %s requires a trusted signature", storage.Name)
+	}
+	if manifest == nil {
+		return "", fmt.Errorf("artifact has no manifest to verify its signature against")
+	}
+	var signature signing.Signature
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return "", fmt.Errorf("parse artifact signature: %w", err)
+	}
+	return signing.Verify(trusted, manifest, &signature)
+}
@@ -2,273 +2,147 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
+	"crypto/sha256"
 	"fmt"
 	"io"
-	"net/http"
-	"os"
 	"path"
-	"path/filepath"
 	"strings"
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	corev1 "k8s.io/api/core/v1"
+	"example.com/backup-operator/internal/storage"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-type s3Config struct {
-	Endpoint        string
-	Bucket          string
-	Prefix          string
-	Region          string
-	ForcePathStyle  bool
-	InsecureSkipTLS bool
-	CABundle        []byte
-	AccessKey       string
-	SecretKey       string
-	SessionToken    string
-}
-
-func storeArtifact(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, artifactPath string, backup *backupObject, timestamp string) (string, error) {
-	relativeBase := path.Join(clusterID(), strings.ToLower(backup.kind), namespaceSegment(backup.namespace), backup.name, timestamp)
+// maxSidecarSize bounds how much of a sidecar file is read back.
+const maxSidecarSize = 1 << 20
 
-	switch storage.Spec.Type {
-	case backupv1alpha1.StorageLocationS3:
-		cfg, err := loadS3Config(ctx, c, storage)
-		if err != nil {
-			return "", err
-		}
-		key := path.Join(cfg.Prefix, relativeBase, artifactFileName)
-		if err := uploadToS3(ctx, cfg, key, artifactPath); err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("s3://%s/%s", cfg.Bucket, key), nil
-	case backupv1alpha1.StorageLocationNFS:
-		nfsPath, err := storeToNFS(storage, relativeBase, artifactPath)
-		if err != nil {
-			return "", err
-		}
-		return fmt.Sprintf("nfs://%s%s", storage.Spec.NFS.Server, nfsPath), nil
-	default:
-		return "", fmt.Errorf("unsupported storage type %q", storage.Spec.Type)
-	}
-}
-
-func loadArtifact(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation, artifactLocation, destPath string) error {
-	switch storage.Spec.Type {
-	case backupv1alpha1.StorageLocationS3:
-		cfg, err := loadS3Config(ctx, c, storage)
-		if err != nil {
-			return err
-		}
-		bucket, key, err := parseS3Location(artifactLocation)
-		if err != nil {
-			return err
-		}
-		cfg.Bucket = bucket
-		return downloadFromS3(ctx, cfg, key, destPath)
-	case backupv1alpha1.StorageLocationNFS:
-		return loadFromNFS(storage, artifactLocation, destPath)
-	default:
-		return fmt.Errorf("unsupported storage type %q", storage.Spec.Type)
+// storeArtifact streams the archive produced by write into the storage location without staging it on local disk.
+// It returns the artifact location and the number of bytes stored.
+func storeArtifact(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, backup *backupObject, timestamp, fileName string, write func(io.Writer) error) (string, int64, error) {
+	backend, err := storage.New(ctx, c, location)
+	if err != nil {
+		return "", 0, err
 	}
-}
-
-func namespaceSegment(ns string) string {
-	if ns == "" {
-		return "cluster"
+	key := path.Join(clusterID(), strings.ToLower(backup.kind), namespaceSegment(backup.namespace), backup.name, timestamp, fileName)
+	size, err := backend.Put(ctx, key, write)
+	if err != nil {
+		return "", 0, err
 	}
-	return ns
+	return backend.Location(key), size, nil
 }
 
-func loadS3Config(ctx context.Context, c client.Client, storage *backupv1alpha1.BackupStorageLocation) (*s3Config, error) {
-	if storage.Spec.S3 == nil {
-		return nil, fmt.Errorf("s3 configuration is missing")
-	}
-
-	cfg := &s3Config{
-		Endpoint:        storage.Spec.S3.Endpoint,
-		Bucket:          storage.Spec.S3.Bucket,
-		Prefix:          strings.Trim(storage.Spec.S3.Prefix, "/"),
-		Region:          storage.Spec.S3.Region,
-		ForcePathStyle:  storage.Spec.S3.ForcePathStyle,
-		InsecureSkipTLS: storage.Spec.S3.InsecureSkipTLS,
-		CABundle:        storage.Spec.S3.CABundle,
-	}
-
-	secretRef := storage.Spec.S3.SecretRef
-	if secretRef.Name != "" {
-		secret := &corev1.Secret{}
-		key := client.ObjectKey{Name: secretRef.Name, Namespace: secretRef.Namespace}
-		if key.Namespace == "" {
-			key.Namespace = operatorNamespace()
-		}
-		if err := c.Get(ctx, key, secret); err != nil {
-			return nil, err
-		}
-		cfg.AccessKey = string(secret.Data["accessKey"])
-		cfg.SecretKey = string(secret.Data["secretKey"])
-		cfg.SessionToken = string(secret.Data["sessionToken"])
-	}
-
-	if cfg.Region == "" {
-		cfg.Region = "us-east-1"
+// openArtifact streams an artifact back from the storage location. The caller must close the reader.
+func openArtifact(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation string) (io.ReadCloser, error) {
+	backend, key, err := artifactKey(ctx, c, location, artifactLocation)
+	if err != nil {
+		return nil, err
 	}
-
-	return cfg, nil
+	return backend.Get(ctx, key)
 }
 
-func uploadToS3(ctx context.Context, cfg *s3Config, key, artifactPath string) error {
-	client, err := buildS3Client(ctx, cfg)
+// artifactKey connects to the storage location and maps an artifact location recorded in status to its key.
+func artifactKey(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation string) (storage.Backend, string, error) {
+	backend, err := storage.New(ctx, c, location)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
-
-	file, err := os.Open(artifactPath)
+	key, err := backend.Key(artifactLocation)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
-	defer file.Close()
-
-	_, err = client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: &cfg.Bucket,
-		Key:    &key,
-		Body:   file,
-	})
-	return err
+	return backend, key, nil
 }
 
-func downloadFromS3(ctx context.Context, cfg *s3Config, key, destPath string) error {
-	client, err := buildS3Client(ctx, cfg)
-	if err != nil {
-		return err
-	}
+// artifactSidecarSuffixes lists the files stored next to an artifact under its name plus a suffix. They are
+// deleted together with the artifact.
+var artifactSidecarSuffixes = []string{digestFileSuffix, signatureFileSuffix, metadataFileSuffix}
 
-	resp, err := client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: &cfg.Bucket,
-		Key:    &key,
-	})
+// storeArtifactSidecar stores a small file next to the artifact, at the artifact location plus suffix.
+func storeArtifactSidecar(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation, suffix string, data []byte) error {
+	backend, key, err := artifactKey(ctx, c, location, artifactLocation)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	out, err := os.Create(destPath)
-	if err != nil {
+	_, err = backend.Put(ctx, key+suffix, func(w io.Writer) error {
+		_, err := w.Write(data)
 		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
+	})
 	return err
 }
 
-func buildS3Client(ctx context.Context, cfg *s3Config) (*s3.Client, error) {
-	customTransport := &http.Transport{}
-	if cfg.InsecureSkipTLS || len(cfg.CABundle) > 0 {
-		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipTLS}
-		if len(cfg.CABundle) > 0 {
-			pool := x509.NewCertPool()
-			pool.AppendCertsFromPEM(cfg.CABundle)
-			tlsConfig.RootCAs = pool
-		}
-		customTransport.TLSClientConfig = tlsConfig
-	}
-
-	customHTTP := &http.Client{Transport: customTransport}
-
-	resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...any) (aws.Endpoint, error) {
-		if service == s3.ServiceID && cfg.Endpoint != "" {
-			return aws.Endpoint{URL: cfg.Endpoint, SigningRegion: cfg.Region, HostnameImmutable: true}, nil
-		}
-		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
-	})
-
-	loadOptions := []func(*config.LoadOptions) error{
-		config.WithRegion(cfg.Region),
-		config.WithEndpointResolverWithOptions(resolver),
-		config.WithHTTPClient(customHTTP),
-	}
-	if cfg.AccessKey != "" && cfg.SecretKey != "" {
-		credProvider := credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, cfg.SessionToken)
-		loadOptions = append(loadOptions, config.WithCredentialsProvider(credProvider))
-	}
-
-	awsCfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+// loadArtifactSidecar reads a file stored next to the artifact. It returns nil data and no error when the file
+// does not exist, which is the case for artifacts written before the sidecar was introduced.
+func loadArtifactSidecar(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation, suffix string) ([]byte, error) {
+	sidecar, err := openArtifact(ctx, c, location, artifactLocation+suffix)
 	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, nil
+		}
 		return nil, err
 	}
-
-	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
-		o.UsePathStyle = cfg.ForcePathStyle
-	}), nil
+	defer sidecar.Close()
+	return io.ReadAll(io.LimitReader(sidecar, maxSidecarSize))
 }
 
-func storeToNFS(storage *backupv1alpha1.BackupStorageLocation, relativeBase, artifactPath string) (string, error) {
-	if storage.Spec.NFS == nil {
-		return "", fmt.Errorf("nfs configuration is missing")
-	}
-	mountPath := getEnvOrDefault("NFS_MOUNT_PATH", "/data")
-	destDir := filepath.Join(mountPath, filepath.FromSlash(relativeBase))
-	if err := os.MkdirAll(destDir, 0700); err != nil {
-		return "", err
+// deleteArtifact removes the artifact, its sidecar files and the plans and results of restores from it.
+func deleteArtifact(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation string) error {
+	backend, key, err := artifactKey(ctx, c, location, artifactLocation)
+	if err != nil {
+		return err
 	}
-	destPath := filepath.Join(destDir, artifactFileName)
-	if err := copyFile(artifactPath, destPath); err != nil {
-		return "", err
+	var keys []string
+	for _, suffix := range artifactSidecarSuffixes {
+		keys = append(keys, key+suffix)
 	}
-	return path.Join(storage.Spec.NFS.Path, relativeBase, artifactFileName), nil
-}
-
-func loadFromNFS(storage *backupv1alpha1.BackupStorageLocation, artifactLocation, destPath string) error {
-	if storage.Spec.NFS == nil {
-		return fmt.Errorf("nfs configuration is missing")
+	for _, infix := range []string{restorePlanInfix, restoreResultsInfix} {
+		err := backend.List(ctx, key+infix, func(object storage.Object) error {
+			keys = append(keys, object.Key)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 	}
-	mountPath := getEnvOrDefault("NFS_MOUNT_PATH", "/data")
-
-	trimmed := strings.TrimPrefix(artifactLocation, "nfs://")
-	parts := strings.SplitN(trimmed, "/", 2)
-	if len(parts) < 2 {
-		return fmt.Errorf("invalid nfs artifact location")
+	for _, sidecar := range keys {
+		if err := backend.Delete(ctx, sidecar); err != nil {
+			return err
+		}
 	}
-	fullPath := "/" + parts[1]
-	basePath := strings.TrimRight(storage.Spec.NFS.Path, "/")
-	relPath := strings.TrimPrefix(fullPath, basePath)
-	relPath = strings.TrimPrefix(relPath, "/")
-
-	sourcePath := filepath.Join(mountPath, filepath.FromSlash(relPath))
-	return copyFile(sourcePath, destPath)
+	return backend.Delete(ctx, key)
 }
 
-func parseS3Location(location string) (string, string, error) {
-	trimmed := strings.TrimPrefix(location, "s3://")
-	parts := strings.SplitN(trimmed, "/", 2)
-	if len(parts) < 2 {
-		return "", "", fmt.Errorf("invalid s3 location %q", location)
+// verifyArtifact reads the stored artifact back and checks that it has the size and SHA-256 digest of the bytes that
+// were streamed into it.
+func verifyArtifact(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, artifactLocation string, size int64, digest string) error {
+	backend, key, err := artifactKey(ctx, c, location, artifactLocation)
+	if err != nil {
+		return err
 	}
-	return parts[0], parts[1], nil
-}
-
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
+	remote, err := backend.Stat(ctx, key)
 	if err != nil {
 		return err
 	}
-	defer in.Close()
-
-	out, err := os.Create(dst)
+	if remote.Size != size {
+		return fmt.Errorf("artifact verification failed: stored %d bytes, expected %d", remote.Size, size)
+	}
+	artifact, err := backend.Get(ctx, key)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	defer artifact.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, artifact); err != nil {
+		return fmt.Errorf("artifact verification failed: read back: %w", err)
+	}
+	if stored := formatDigest(hash.Sum(nil)); stored != digest {
+		return fmt.Errorf("artifact verification failed: stored digest %s, expected %s", stored, digest)
+	}
+	return nil
+}
 
-	if _, err := io.Copy(out, in); err != nil {
-		return err
+func namespaceSegment(ns string) string {
+	if ns == "" {
+		return "cluster"
 	}
-	return out.Sync()
+	return ns
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const memoryStorageType backupv1alpha1.StorageLocationType = "Memory"
+
+// newMemoryLocation registers a fresh in-memory backend for memoryStorageType and returns a location that
+// encrypts with key-1 from a Secret in the returned client.
+func newMemoryLocation(t *testing.T) (client.Client, *backupv1alpha1.BackupStorageLocation) {
+	t.Helper()
+	backend := storage.NewMemory(t.Name())
+	storage.Register(memoryStorageType, storage.Driver{
+		Validate: func(*backupv1alpha1.BackupStorageLocation) error { return nil },
+		New: func(context.Context, client.Reader, *backupv1alpha1.BackupStorageLocation) (storage.Backend, error) {
+			return backend, nil
+		},
+	})
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "artifact-keys", Namespace: "default"},
+		Data: map[string][]byte{
+			"key-1": bytes.Repeat([]byte{7}, 32),
+			// A malformed entry must not affect artifacts sealed with the other keys.
+			"broken": []byte("not a key"),
+		},
+	}
+	location := &backupv1alpha1.BackupStorageLocation{
+		ObjectMeta: metav1.ObjectMeta{Name: "memory"},
+		Spec: backupv1alpha1.BackupStorageLocationSpec{
+			Type: memoryStorageType,
+			Encryption: &backupv1alpha1.EncryptionSpec{
+				SecretRef: corev1.SecretReference{Name: "artifact-keys", Namespace: "default"},
+				KeyID:     "key-1",
+			},
+		},
+	}
+	return fake.NewClientBuilder().WithObjects(secret).Build(), location
+}
+
+// writeTestArtifact stores an encrypted artifact with the given entries the way the backup worker does, and
+// returns the status a backup would record for it.
+func writeTestArtifact(t *testing.T, c client.Client, location *backupv1alpha1.BackupStorageLocation, entries map[string]string, names ...string) backupv1alpha1.BackupStatus {
+	t.Helper()
+	ctx := context.Background()
+	keyID, key, err := activeEncryptionKey(ctx, c, location.Spec.Encryption)
+	if err != nil {
+		t.Fatalf("activeEncryptionKey: %v", err)
+	}
+	backup := &backupObject{kind: "Backup", name: "app1-backup", namespace: "app1"}
+	digest := sha256.New()
+	artifactLocation, _, err := storeArtifact(ctx, c, location, backup, "20260102T030405Z", artifactFileName+encryptedFileSuffix, func(w io.Writer) error {
+		return sealArtifact(io.MultiWriter(w, digest), keyID, key, func(w io.Writer) error {
+			archive := newArtifactWriter(w)
+			for _, name := range names {
+				if err := archive.add(name, []byte(entries[name])); err != nil {
+					return err
+				}
+			}
+			return archive.Close()
+		})
+	})
+	if err != nil {
+		t.Fatalf("storeArtifact: %v", err)
+	}
+	return backupv1alpha1.BackupStatus{ArtifactLocation: artifactLocation, ArtifactDigest: formatDigest(digest.Sum(nil))}
+}
+
+// readTestArtifact reads every entry back through openBackupArtifact, checking each against verifier.
+func readTestArtifact(c client.Client, location *backupv1alpha1.BackupStorageLocation, status backupv1alpha1.BackupStatus, verifier *entryVerifier) (map[string]string, error) {
+	artifact, err := openBackupArtifact(context.Background(), c, location, backupv1alpha1.BackupSpec{}, status.ArtifactLocation)
+	if err != nil {
+		return nil, err
+	}
+	defer artifact.Close()
+	read := map[string]string{}
+	err = readArtifact(artifact, func(name string, entry io.Reader) error {
+		entry, err := verifier.verify(name, entry)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(entry)
+		read[name] = string(data)
+		return err
+	})
+	if err == nil {
+		err = verifier.complete()
+	}
+	return read, err
+}
+
+func TestArtifactRoundTrip(t *testing.T) {
+	c, location := newMemoryLocation(t)
+	entries := map[string]string{
+		"metadata.json":                    `{"name":"app1-backup"}`,
+		"resources/configmaps/app1/a.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n",
+	}
+	status := writeTestArtifact(t, c, location, entries, "metadata.json", "resources/configmaps/app1/a.yaml")
+
+	report, err := verifyArtifactIntegrity(context.Background(), c, location, backupv1alpha1.BackupSpec{}, status)
+	if err != nil {
+		t.Fatalf("verifyArtifactIntegrity: %v", err)
+	}
+	if !report.hasDigest || !report.hasManifest || report.entries != len(entries) {
+		t.Fatalf("report = %s, want digest and %d manifest entries verified", report.message(), len(entries))
+	}
+
+	read, err := readTestArtifact(c, location, status, newEntryVerifier(report))
+	if err != nil {
+		t.Fatalf("read artifact: %v", err)
+	}
+	for name, want := range entries {
+		if read[name] != want {
+			t.Errorf("entry %s = %q, want %q", name, read[name], want)
+		}
+	}
+	if _, ok := read[manifestEntryName]; !ok {
+		t.Errorf("artifact has no %s entry", manifestEntryName)
+	}
+}
+
+func TestArtifactDigestMismatch(t *testing.T) {
+	c, location := newMemoryLocation(t)
+	status := writeTestArtifact(t, c, location, map[string]string{"metadata.json": "{}"}, "metadata.json")
+	status.ArtifactDigest = formatDigest(make([]byte, sha256.Size))
+
+	_, err := verifyArtifactIntegrity(context.Background(), c, location, backupv1alpha1.BackupSpec{}, status)
+	if !isIntegrityError(err) {
+		t.Fatalf("verifyArtifactIntegrity error = %v, want an integrity error", err)
+	}
+}
+
+func TestArtifactReplacedAfterVerification(t *testing.T) {
+	c, location := newMemoryLocation(t)
+	names := []string{"metadata.json", "resources/configmaps/app1/a.yaml"}
+	original := writeTestArtifact(t, c, location, map[string]string{
+		"metadata.json":                    "{}",
+		"resources/configmaps/app1/a.yaml": "kind: ConfigMap\n",
+	}, names...)
+	report, err := verifyArtifactIntegrity(context.Background(), c, location, backupv1alpha1.BackupSpec{}, original)
+	if err != nil {
+		t.Fatalf("verifyArtifactIntegrity: %v", err)
+	}
+
+	// The same backup and timestamp map to the same key, so this overwrites the verified artifact with one whose
+	// object differs, as if it were swapped in storage.
+	writeTestArtifact(t, c, location, map[string]string{
+		"metadata.json":                    "{}",
+		"resources/configmaps/app1/a.yaml": "kind: Secret\n",
+	}, names...)
+
+	read, err := readTestArtifact(c, location, original, newEntryVerifier(report))
+	if !isIntegrityError(err) {
+		t.Fatalf("read artifact error = %v, want an integrity error", err)
+	}
+	if _, ok := read["resources/configmaps/app1/a.yaml"]; ok {
+		t.Errorf("changed entry was returned before it was verified")
+	}
+}
+
+func TestVerifyArtifact(t *testing.T) {
+	c, location := newMemoryLocation(t)
+	status := writeTestArtifact(t, c, location, map[string]string{"metadata.json": "{}"}, "metadata.json")
+	ctx := context.Background()
+	backend, key, err := artifactKey(ctx, c, location, status.ArtifactLocation)
+	if err != nil {
+		t.Fatalf("artifactKey: %v", err)
+	}
+	object, err := backend.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("stat artifact: %v", err)
+	}
+
+	if err := verifyArtifact(ctx, c, location, status.ArtifactLocation, object.Size, status.ArtifactDigest); err != nil {
+		t.Fatalf("verifyArtifact: %v", err)
+	}
+	// Same size, different bytes: only the digest tells them apart.
+	if err := verifyArtifact(ctx, c, location, status.ArtifactLocation, object.Size, formatDigest(make([]byte, sha256.Size))); err == nil {
+		t.Fatalf("verifyArtifact accepted an artifact whose digest does not match")
+	}
+}
@@ -6,10 +6,14 @@ import (
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
 	"example.com/backup-operator/internal/resolve"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -30,10 +34,22 @@ func (r *BackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	logger.V(1).Info("reconciling Backup", "name", backup.Name, "namespace", backup.Namespace)
 
 	if !backup.DeletionTimestamp.IsZero() {
+		return finalizeBackup(ctx, r.Client, &backup, "Backup", backup.Spec, &backup.Status)
+	}
+
+	if controllerutil.AddFinalizer(&backup, backupv1alpha1.BackupCleanupFinalizer) {
+		if err := r.Update(ctx, &backup); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Finished resources are left alone so the worker Job being cleaned up does not start a new run. Synced
+	// resources record an artifact that already exists and never run.
+	if backupFinished(backup.Status) || isSyncedBackup(&backup) {
 		return ctrl.Result{}, nil
 	}
 
-	job, err := findJob(ctx, r.Client, "Backup", backup.Name, backup.Namespace, backup.UID)
+	job, err := findJob(ctx, r.Client, jobTypeBackup, "Backup", backup.Name, backup.Namespace, backup.UID)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -47,6 +63,14 @@ func (r *BackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		if err != nil {
 			return r.failBackup(ctx, &backup, fmt.Sprintf("storage location error: %v", err))
 		}
+		if storage.Status.Phase == backupv1alpha1.StorageLocationUnavailable {
+			if holdBackupForStorage(&backup.Status, backup.Generation, storage) {
+				if err := r.Status().Update(ctx, &backup); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{RequeueAfter: storageHoldRequeue}, nil
+		}
 
 		job, err = buildBackupJob("Backup", backup.Name, backup.Namespace, backup.UID, storage)
 		if err != nil {
@@ -74,7 +98,8 @@ func (r *BackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, nil
 	}
 
-	if job.Status.Succeeded > 0 && backup.Status.Phase != backupv1alpha1.BackupPhaseCompleted {
+	// The worker records Completed or Failed itself; only fill in a phase it never got to write.
+	if job.Status.Succeeded > 0 && !backupFinished(backup.Status) {
 		backup.Status.Phase = backupv1alpha1.BackupPhaseCompleted
 		backup.Status.ObservedGeneration = backup.Generation
 		if err := r.Status().Update(ctx, &backup); err != nil {
@@ -82,8 +107,8 @@ func (r *BackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 	}
 
-	if job.Status.Failed > 0 && backup.Status.Phase != backupv1alpha1.BackupPhaseFailed {
-		return r.failBackup(ctx, &backup, "backup job failed")
+	if job.Status.Failed > 0 && !backupFinished(backup.Status) {
+		return r.failBackup(ctx, &backup, jobFailureMessage(ctx, r.Client, job, "backup job failed"))
 	}
 
 	return ctrl.Result{}, nil
@@ -95,6 +120,13 @@ func (r *BackupReconciler) failBackup(ctx context.Context, backup *backupv1alpha
 	backup.Status.Message = message
 	backup.Status.CompletedAt = &now
 	backup.Status.ObservedGeneration = backup.Generation
+	meta.SetStatusCondition(&backup.Status.Conditions, metav1.Condition{
+		Type:               backupv1alpha1.BackupConditionCompleted,
+		Status:             metav1.ConditionFalse,
+		Reason:             backupv1alpha1.ConditionReasonFailed,
+		Message:            message,
+		ObservedGeneration: backup.Generation,
+	})
 	if err := r.Status().Update(ctx, backup); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -104,5 +136,11 @@ func (r *BackupReconciler) failBackup(ctx context.Context, backup *backupv1alpha
 func (r *BackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&backupv1alpha1.Backup{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(enqueueOwnerForJob("Backup", jobTypeBackup, jobTypeCleanup))).
 		Complete(r)
 }
+
+// isSyncedBackup reports whether a backup resource was recreated by catalog sync from an artifact in storage.
+func isSyncedBackup(obj client.Object) bool {
+	return obj.GetLabels()[backupv1alpha1.BackupSyncedLabel] == "true"
+}
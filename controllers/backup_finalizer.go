@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/resolve"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// finalizeBackup handles a Backup or ClusterBackup that is being deleted. With deletionPolicy Delete it runs a
+// cleanup Job, which releases the finalizer itself; if the Job cannot run or fails, the error is recorded as the
+// ArtifactsDeleted condition and the finalizer is released anyway so deletion never hangs.
+func finalizeBackup(ctx context.Context, c client.Client, obj client.Object, kind string, spec backupv1alpha1.BackupSpec, status *backupv1alpha1.BackupStatus) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(obj, backupv1alpha1.BackupCleanupFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if spec.DeletionPolicy == backupv1alpha1.BackupDeletionPolicyRetain {
+		logger.Info("retaining artifact and volume snapshots", "kind", kind, "name", obj.GetName(), "artifact", status.ArtifactLocation)
+		return ctrl.Result{}, releaseBackupFinalizer(ctx, c, obj)
+	}
+
+	job, err := findJob(ctx, c, jobTypeCleanup, kind, obj.GetName(), obj.GetNamespace(), obj.GetUID())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if job == nil {
+		var storage *backupv1alpha1.BackupStorageLocation
+		if status.ArtifactLocation != "" {
+			storageName := ""
+			if spec.StorageRef != nil {
+				storageName = spec.StorageRef.Name
+			}
+			storage, err = resolve.StorageLocation(ctx, c, storageName)
+			if err != nil {
+				return abandonBackupCleanup(ctx, c, obj, status, fmt.Sprintf("storage location error: %v", err))
+			}
+		}
+
+		job, err = buildCleanupJob(kind, obj.GetName(), obj.GetNamespace(), obj.GetUID(), storage)
+		if err != nil {
+			return abandonBackupCleanup(ctx, c, obj, status, err.Error())
+		}
+		if err := c.Create(ctx, job); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Info("cleaning up deleted backup", "kind", kind, "name", obj.GetName(), "artifact", status.ArtifactLocation)
+		return ctrl.Result{}, nil
+	}
+
+	if job.Status.Failed > 0 {
+		return abandonBackupCleanup(ctx, c, obj, status, jobFailureMessage(ctx, c, job, "cleanup job failed"))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func abandonBackupCleanup(ctx context.Context, c client.Client, obj client.Object, status *backupv1alpha1.BackupStatus, message string) (ctrl.Result, error) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               backupv1alpha1.BackupConditionArtifactsDeleted,
+		Status:             metav1.ConditionFalse,
+		Reason:             backupv1alpha1.ConditionReasonFailed,
+		Message:            message,
+		ObservedGeneration: obj.GetGeneration(),
+	})
+	if err := c.Status().Update(ctx, obj); err != nil {
+		return ctrl.Result{}, err
+	}
+	log.FromContext(ctx).Info("backup cleanup failed, releasing finalizer", "name", obj.GetName(), "message", message)
+	return ctrl.Result{}, releaseBackupFinalizer(ctx, c, obj)
+}
+
+func releaseBackupFinalizer(ctx context.Context, c client.Client, obj client.Object) error {
+	if !controllerutil.RemoveFinalizer(obj, backupv1alpha1.BackupCleanupFinalizer) {
+		return nil
+	}
+	return client.IgnoreNotFound(c.Update(ctx, obj))
+}
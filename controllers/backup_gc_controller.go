@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/resolve"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const gcJobFailedMessage = "garbage collection job failed"
+
+// BackupGCReconciler deletes Backup resources and their artifacts once they expire.
+type BackupGCReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// DryRun only logs expired backups instead of collecting them.
+	DryRun bool
+}
+
+func (r *BackupGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var backup backupv1alpha1.Backup
+	if err := r.Get(ctx, req.NamespacedName, &backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !backup.DeletionTimestamp.IsZero() || !backupFinished(backup.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	expiresAt := backupExpiry(backup.Spec, backup.Status)
+	if !expiresAt.Equal(backup.Status.ExpiresAt) {
+		backup.Status.ExpiresAt = expiresAt
+		if err := r.Status().Update(ctx, &backup); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if expiresAt == nil {
+		return ctrl.Result{}, nil
+	}
+	if remaining := time.Until(expiresAt.Time); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	if r.DryRun {
+		logger.Info("dry-run: would garbage collect expired Backup", "name", backup.Name, "namespace", backup.Namespace,
+			"expiresAt", expiresAt.Time, "artifact", backup.Status.ArtifactLocation)
+		return ctrl.Result{}, nil
+	}
+
+	job, err := findJob(ctx, r.Client, jobTypeGC, "Backup", backup.Name, backup.Namespace, backup.UID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if job != nil {
+		if job.Status.Failed > 0 {
+			if message := jobFailureMessage(ctx, r.Client, job, gcJobFailedMessage); backup.Status.Message != message {
+				backup.Status.Message = message
+				if err := r.Status().Update(ctx, &backup); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	storageName := ""
+	if backup.Spec.StorageRef != nil {
+		storageName = backup.Spec.StorageRef.Name
+	}
+	storage, err := resolve.StorageLocation(ctx, r.Client, storageName)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("storage location error: %w", err)
+	}
+
+	job, err = buildGCJob("Backup", backup.Name, backup.Namespace, backup.UID, storage)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("garbage collecting expired Backup", "name", backup.Name, "namespace", backup.Namespace, "expiresAt", expiresAt.Time)
+	return ctrl.Result{}, nil
+}
+
+func (r *BackupGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("backup-gc").
+		For(&backupv1alpha1.Backup{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(enqueueOwnerForJob("Backup", jobTypeGC))).
+		Complete(r)
+}
+
+func backupFinished(status backupv1alpha1.BackupStatus) bool {
+	switch status.Phase {
+	case backupv1alpha1.BackupPhaseCompleted, backupv1alpha1.BackupPhasePartiallyFailed, backupv1alpha1.BackupPhaseFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// backupExpiry returns when a finished backup expires. RetainUntil takes precedence over CompletedAt+TTL.
+func backupExpiry(spec backupv1alpha1.BackupSpec, status backupv1alpha1.BackupStatus) *metav1.Time {
+	if spec.RetainUntil != nil {
+		expiresAt := spec.RetainUntil.Rfc3339Copy()
+		return &expiresAt
+	}
+	if spec.TTL != nil && status.CompletedAt != nil {
+		expiresAt := metav1.NewTime(status.CompletedAt.Add(spec.TTL.Duration)).Rfc3339Copy()
+		return &expiresAt
+	}
+	return nil
+}
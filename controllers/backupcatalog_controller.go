@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const defaultSyncInterval = 10 * time.Minute
+
+// BackupCatalogReconciler periodically runs a catalog Job for each available BackupStorageLocation, which recreates
+// Backup and ClusterBackup resources for artifacts that no resource in the cluster refers to.
+type BackupCatalogReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func (r *BackupCatalogReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var location backupv1alpha1.BackupStorageLocation
+	if err := r.Get(ctx, req.NamespacedName, &location); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !location.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	job, err := findJob(ctx, r.Client, jobTypeCatalog, "BackupStorageLocation", location.Name, "", location.UID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if job != nil {
+		return r.finishCatalogJob(ctx, &location, job)
+	}
+
+	due, wait := syncDue(&location, time.Now())
+	if !due {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+	// Syncing an unreachable location would only fail; the location is reconciled again once it is probed.
+	if location.Status.Phase != backupv1alpha1.StorageLocationAvailable {
+		return ctrl.Result{}, nil
+	}
+
+	job, err = buildCatalogJob(&location)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return ctrl.Result{}, err
+	}
+	logger.Info("syncing backup catalog", "location", location.Name)
+	return ctrl.Result{}, nil
+}
+
+// finishCatalogJob waits for a catalog Job and deletes it once it is done. The catalog worker records its own
+// result; a failure it never got to record is filled in from the Job.
+func (r *BackupCatalogReconciler) finishCatalogJob(ctx context.Context, location *backupv1alpha1.BackupStorageLocation, job *batchv1.Job) (ctrl.Result, error) {
+	if job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+		return ctrl.Result{}, nil
+	}
+	recorded := location.Status.LastSynced != nil && !location.Status.LastSynced.Before(&job.CreationTimestamp)
+	if job.Status.Failed > 0 && !recorded {
+		message := jobFailureMessage(ctx, r.Client, job, "catalog sync job failed")
+		err := storage.PatchStatus(ctx, r.Client, location, func(location *backupv1alpha1.BackupStorageLocation) {
+			now := metav1.Now()
+			location.Status.LastSynced = &now
+			meta.SetStatusCondition(&location.Status.Conditions, metav1.Condition{
+				Type:               backupv1alpha1.StorageLocationConditionCatalogSynced,
+				Status:             metav1.ConditionFalse,
+				Reason:             backupv1alpha1.ConditionReasonFailed,
+				Message:            message,
+				ObservedGeneration: location.Generation,
+			})
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		return ctrl.Result{}, err
+	}
+	if interval := syncInterval(location); interval > 0 {
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *BackupCatalogReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("backup-catalog").
+		For(&backupv1alpha1.BackupStorageLocation{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(enqueueOwnerForJob("BackupStorageLocation", jobTypeCatalog))).
+		Complete(r)
+}
+
+func syncInterval(location *backupv1alpha1.BackupStorageLocation) time.Duration {
+	if location.Spec.SyncInterval == nil {
+		return defaultSyncInterval
+	}
+	return location.Spec.SyncInterval.Duration
+}
+
+// syncDue reports whether the location's catalog should be synced now, and otherwise how long until it should be.
+// A zero wait with nothing due means catalog sync is disabled.
+func syncDue(location *backupv1alpha1.BackupStorageLocation, now time.Time) (bool, time.Duration) {
+	interval := syncInterval(location)
+	if interval <= 0 {
+		return false, 0
+	}
+	if location.Status.LastSynced == nil {
+		return true, 0
+	}
+	if wait := location.Status.LastSynced.Add(interval).Sub(now); wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
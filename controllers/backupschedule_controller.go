@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// BackupScheduleReconciler reconciles BackupSchedule resources.
+type BackupScheduleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func (r *BackupScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var schedule backupv1alpha1.BackupSchedule
+	if err := r.Get(ctx, req.NamespacedName, &schedule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcileSchedule(ctx, r.Client, r.Scheme, &schedule, scheduleTarget{
+		kind:   "Backup",
+		spec:   schedule.Spec.ScheduleSpec,
+		status: &schedule.Status.ScheduleStatus,
+		list: func(ctx context.Context) ([]scheduledChild, error) {
+			var children backupv1alpha1.BackupList
+			if err := r.List(ctx, &children, client.InNamespace(schedule.Namespace), client.MatchingLabels{labelScheduleName: schedule.Name}); err != nil {
+				return nil, err
+			}
+			result := make([]scheduledChild, 0, len(children.Items))
+			for i := range children.Items {
+				result = append(result, scheduledChild{obj: &children.Items[i], status: children.Items[i].Status})
+			}
+			return result, nil
+		},
+		newBackup: func(objectMeta metav1.ObjectMeta) client.Object {
+			return &backupv1alpha1.Backup{ObjectMeta: objectMeta, Spec: *schedule.Spec.Template.DeepCopy()}
+		},
+	})
+}
+
+func (r *BackupScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&backupv1alpha1.BackupSchedule{}).
+		Owns(&backupv1alpha1.Backup{}).
+		Complete(r)
+}
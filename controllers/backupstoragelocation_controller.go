@@ -2,16 +2,34 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/storage"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-// BackupStorageLocationReconciler reconciles BackupStorageLocation resources.
+const (
+	defaultValidationInterval = 5 * time.Minute
+	// probeTimeout bounds a probe run by the controller itself, so an unresponsive endpoint cannot stall it.
+	probeTimeout = 30 * time.Second
+	// probeJobDeadline bounds a probe Job, which can hang on an unreachable NFS server.
+	probeJobDeadline = int64(300)
+	// storageHoldRequeue is how often a backup held for an unavailable location checks it again.
+	storageHoldRequeue = 30 * time.Second
+)
+
+// BackupStorageLocationReconciler reconciles BackupStorageLocation resources. It probes object stores directly and
+// NFS locations through a probe Job that mounts the export.
 type BackupStorageLocationReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
@@ -26,44 +44,144 @@ func (r *BackupStorageLocationReconciler) Reconcile(ctx context.Context, req ctr
 	}
 
 	logger.V(1).Info("reconciling BackupStorageLocation", "name", location.Name)
-	status := location.Status
-	now := metav1.Now()
-	status.LastValidated = &now
-	status.ObservedGeneration = location.Generation
-
-	switch location.Spec.Type {
-	case backupv1alpha1.StorageLocationS3:
-		if location.Spec.S3 == nil || location.Spec.S3.Bucket == "" {
-			status.Phase = backupv1alpha1.StorageLocationUnavailable
-			status.Message = "s3 storage location requires bucket"
-		} else {
-			status.Phase = backupv1alpha1.StorageLocationAvailable
-			status.Message = "s3 storage location configured"
-		}
-	case backupv1alpha1.StorageLocationNFS:
-		if location.Spec.NFS == nil || location.Spec.NFS.Server == "" || location.Spec.NFS.Path == "" {
-			status.Phase = backupv1alpha1.StorageLocationUnavailable
-			status.Message = "nfs storage location requires server and path"
-		} else {
-			status.Phase = backupv1alpha1.StorageLocationAvailable
-			status.Message = "nfs storage location configured"
+
+	job, err := findJob(ctx, r.Client, jobTypeProbe, "BackupStorageLocation", location.Name, "", location.UID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if job != nil {
+		return r.finishProbeJob(ctx, &location, job)
+	}
+
+	due, wait := validationDue(&location, time.Now())
+	if !due && !volumeReplacing(&location) {
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	if err := storage.Validate(&location); err != nil {
+		// Only a spec change can fix this, and that triggers a reconcile of its own.
+		return ctrl.Result{}, r.recordAvailability(ctx, &location, backupv1alpha1.ConditionReasonInvalidConfiguration, 0, err)
+	}
+	if err := r.ensureVolume(ctx, &location); errors.Is(err, errNFSVolumeReplacing) {
+		// Backups are held meanwhile, so none start on a claim that is going away.
+		return ctrl.Result{RequeueAfter: storageHoldRequeue}, r.recordAvailability(ctx, &location, backupv1alpha1.ConditionReasonVolumeReplacing, 0, err)
+	} else if err != nil {
+		return r.requeue(&location), r.recordAvailability(ctx, &location, backupv1alpha1.ConditionReasonFailed, 0, err)
+	}
+
+	if location.Spec.Type == backupv1alpha1.StorageLocationNFS {
+		job, err := buildProbeJob(&location)
+		if err != nil {
+			return ctrl.Result{}, err
 		}
-	default:
-		status.Phase = backupv1alpha1.StorageLocationUnavailable
-		status.Message = "unsupported storage location type"
+		return ctrl.Result{}, r.Create(ctx, job)
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	backend, err := storage.New(probeCtx, r.Client, &location)
+	var latency time.Duration
+	if err == nil {
+		latency, err = storage.Probe(probeCtx, &location, backend)
 	}
+	return r.requeue(&location), r.recordAvailability(ctx, &location, backupv1alpha1.ConditionReasonFailed, latency, err)
+}
 
-	location.Status = status
-	if err := r.Status().Update(ctx, &location); err != nil {
-		logger.Error(err, "unable to update BackupStorageLocation status")
+// finishProbeJob waits for a probe Job and deletes it once it is done. The probe worker records its own result;
+// a failure it never got to record is filled in from the Job.
+func (r *BackupStorageLocationReconciler) finishProbeJob(ctx context.Context, location *backupv1alpha1.BackupStorageLocation, job *batchv1.Job) (ctrl.Result, error) {
+	if job.Status.Succeeded == 0 && job.Status.Failed == 0 {
+		return ctrl.Result{}, nil
+	}
+	recorded := location.Status.LastValidated != nil && !location.Status.LastValidated.Before(&job.CreationTimestamp)
+	if job.Status.Failed > 0 && !recorded {
+		err := errors.New(jobFailureMessage(ctx, r.Client, job, "storage probe job failed"))
+		if err := r.recordAvailability(ctx, location, backupv1alpha1.ConditionReasonFailed, 0, err); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
 		return ctrl.Result{}, err
 	}
+	return r.requeue(location), nil
+}
+
+func (r *BackupStorageLocationReconciler) recordAvailability(ctx context.Context, location *backupv1alpha1.BackupStorageLocation, reason string, latency time.Duration, probeErr error) error {
+	err := storage.PatchStatus(ctx, r.Client, location, func(location *backupv1alpha1.BackupStorageLocation) {
+		storage.SetAvailability(location, reason, latency, probeErr)
+	})
+	if err != nil {
+		log.FromContext(ctx).Error(err, "unable to update BackupStorageLocation status")
+		return err
+	}
+	return nil
+}
+
+// requeue schedules the next periodic probe.
+func (r *BackupStorageLocationReconciler) requeue(location *backupv1alpha1.BackupStorageLocation) ctrl.Result {
+	return ctrl.Result{RequeueAfter: validationInterval(location)}
+}
 
-	return ctrl.Result{}, nil
+// ensureVolume provisions the PersistentVolume that NFS locations with mount options or credentials are mounted from.
+func (r *BackupStorageLocationReconciler) ensureVolume(ctx context.Context, location *backupv1alpha1.BackupStorageLocation) error {
+	if location.Spec.Type != backupv1alpha1.StorageLocationNFS || !nfsNeedsVolume(location.Spec.NFS) {
+		return nil
+	}
+	return ensureNFSVolume(ctx, r.Client, r.Scheme, location)
 }
 
 func (r *BackupStorageLocationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&backupv1alpha1.BackupStorageLocation{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(enqueueOwnerForJob("BackupStorageLocation", jobTypeProbe))).
 		Complete(r)
 }
+
+func validationInterval(location *backupv1alpha1.BackupStorageLocation) time.Duration {
+	if location.Spec.ValidationInterval == nil {
+		return defaultValidationInterval
+	}
+	return location.Spec.ValidationInterval.Duration
+}
+
+// validationDue reports whether the location should be probed now, and otherwise how long until it should be.
+// A zero wait means it is only probed again when its spec changes.
+func validationDue(location *backupv1alpha1.BackupStorageLocation, now time.Time) (bool, time.Duration) {
+	status := location.Status
+	if status.LastValidated == nil || status.ObservedGeneration != location.Generation {
+		return true, 0
+	}
+	interval := validationInterval(location)
+	if interval <= 0 {
+		return false, 0
+	}
+	if wait := status.LastValidated.Add(interval).Sub(now); wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
+
+// volumeReplacing reports whether the location is waiting for the volume of its previous NFS export to be deleted,
+// which is checked again without waiting for the validation interval.
+func volumeReplacing(location *backupv1alpha1.BackupStorageLocation) bool {
+	condition := meta.FindStatusCondition(location.Status.Conditions, backupv1alpha1.StorageLocationConditionAvailable)
+	return condition != nil && condition.Reason == backupv1alpha1.ConditionReasonVolumeReplacing
+}
+
+// holdBackupForStorage keeps a backup Pending while its storage location is Unavailable, instead of starting a
+// worker that would fail to write the artifact. It reports whether the status changed.
+func holdBackupForStorage(status *backupv1alpha1.BackupStatus, generation int64, location *backupv1alpha1.BackupStorageLocation) bool {
+	message := fmt.Sprintf("waiting for BackupStorageLocation %s to become available: %s", location.Name, location.Status.Message)
+	changed := status.Phase != backupv1alpha1.BackupPhasePending || status.Message != message
+	status.Phase = backupv1alpha1.BackupPhasePending
+	status.Message = message
+	status.ObservedGeneration = generation
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               backupv1alpha1.BackupConditionStorageResolved,
+		Status:             metav1.ConditionFalse,
+		Reason:             backupv1alpha1.ConditionReasonStorageUnavailable,
+		Message:            message,
+		ObservedGeneration: generation,
+	})
+	return changed
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/resolve"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ClusterBackupGCReconciler deletes ClusterBackup resources and their artifacts once they expire.
+type ClusterBackupGCReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// DryRun only logs expired backups instead of collecting them.
+	DryRun bool
+}
+
+func (r *ClusterBackupGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var backup backupv1alpha1.ClusterBackup
+	if err := r.Get(ctx, req.NamespacedName, &backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !backup.DeletionTimestamp.IsZero() || !backupFinished(backup.Status.BackupStatus) {
+		return ctrl.Result{}, nil
+	}
+
+	expiresAt := backupExpiry(backup.Spec.BackupSpec, backup.Status.BackupStatus)
+	if !expiresAt.Equal(backup.Status.ExpiresAt) {
+		backup.Status.ExpiresAt = expiresAt
+		if err := r.Status().Update(ctx, &backup); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if expiresAt == nil {
+		return ctrl.Result{}, nil
+	}
+	if remaining := time.Until(expiresAt.Time); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	if r.DryRun {
+		logger.Info("dry-run: would garbage collect expired ClusterBackup", "name", backup.Name,
+			"expiresAt", expiresAt.Time, "artifact", backup.Status.ArtifactLocation)
+		return ctrl.Result{}, nil
+	}
+
+	job, err := findJob(ctx, r.Client, jobTypeGC, "ClusterBackup", backup.Name, "", backup.UID)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if job != nil {
+		if job.Status.Failed > 0 {
+			if message := jobFailureMessage(ctx, r.Client, job, gcJobFailedMessage); backup.Status.Message != message {
+				backup.Status.Message = message
+				if err := r.Status().Update(ctx, &backup); err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	storageName := ""
+	if backup.Spec.StorageRef != nil {
+		storageName = backup.Spec.StorageRef.Name
+	}
+	storage, err := resolve.StorageLocation(ctx, r.Client, storageName)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("storage location error: %w", err)
+	}
+
+	job, err = buildGCJob("ClusterBackup", backup.Name, "", backup.UID, storage)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Create(ctx, job); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("garbage collecting expired ClusterBackup", "name", backup.Name, "expiresAt", expiresAt.Time)
+	return ctrl.Result{}, nil
+}
+
+func (r *ClusterBackupGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("clusterbackup-gc").
+		For(&backupv1alpha1.ClusterBackup{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(enqueueOwnerForJob("ClusterBackup", jobTypeGC))).
+		Complete(r)
+}
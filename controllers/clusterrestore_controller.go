@@ -7,10 +7,12 @@ import (
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
 	"example.com/backup-operator/internal/resolve"
+	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -30,11 +32,12 @@ func (r *ClusterRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	logger.V(1).Info("reconciling ClusterRestore", "name", restore.Name)
 
-	if !restore.DeletionTimestamp.IsZero() {
+	// Finished resources are left alone so the worker Job being cleaned up does not start a new run.
+	if !restore.DeletionTimestamp.IsZero() || restoreFinished(restore.Status.RestoreStatus) {
 		return ctrl.Result{}, nil
 	}
 
-	job, err := findJob(ctx, r.Client, "ClusterRestore", restore.Name, "", restore.UID)
+	job, err := findJob(ctx, r.Client, jobTypeRestore, "ClusterRestore", restore.Name, "", restore.UID)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -44,9 +47,16 @@ func (r *ClusterRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return r.failClusterRestore(ctx, &restore, err.Error())
 	}
 
-	if sourceBackup.Status.ArtifactLocation == "" {
-		logger.V(1).Info("backup artifact location not ready yet", "backup", sourceBackup.Name)
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	// Artifact sources are looked up in storage by the worker, which can reach it. A backup records its artifact
+	// as soon as it is uploaded, so it is only restored from once it has finished without failing.
+	if restore.Spec.SourceRef.Kind != backupv1alpha1.RestoreSourceArtifact {
+		if sourceBackup.Status.Phase == backupv1alpha1.BackupPhaseFailed {
+			return r.failRestore(ctx, &restore, fmt.Sprintf("backup %s failed", sourceBackup.Name))
+		}
+		if sourceBackup.Status.ArtifactLocation == "" || !backupFinished(sourceBackup.Status) {
+			logger.V(1).Info("backup artifact not ready yet", "backup", sourceBackup.Name)
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
 	}
 
 	if job == nil {
@@ -85,7 +95,8 @@ func (r *ClusterRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return ctrl.Result{}, nil
 	}
 
-	if job.Status.Succeeded > 0 && restore.Status.Phase != backupv1alpha1.RestorePhaseCompleted {
+	// The worker records Completed or Failed itself; only fill in a phase it never got to write.
+	if job.Status.Succeeded > 0 && !restoreFinished(restore.Status.RestoreStatus) {
 		restore.Status.Phase = backupv1alpha1.RestorePhaseCompleted
 		restore.Status.ObservedGeneration = restore.Generation
 		if err := r.Status().Update(ctx, &restore); err != nil {
@@ -93,8 +104,8 @@ func (r *ClusterRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 	}
 
-	if job.Status.Failed > 0 && restore.Status.Phase != backupv1alpha1.RestorePhaseFailed {
-		return r.failClusterRestore(ctx, &restore, "restore job failed")
+	if job.Status.Failed > 0 && !restoreFinished(restore.Status.RestoreStatus) {
+		return r.failClusterRestore(ctx, &restore, jobFailureMessage(ctx, r.Client, job, "restore job failed"))
 	}
 
 	return ctrl.Result{}, nil
@@ -122,6 +133,13 @@ func (r *ClusterRestoreReconciler) getBackupForRestore(ctx context.Context, rest
 			Status:     clusterBackup.Status.BackupStatus,
 		}
 		return &converted, nil
+	case backupv1alpha1.RestoreSourceArtifact:
+		if restore.Spec.SourceRef.Artifact == nil {
+			return nil, fmt.Errorf("sourceRef.artifact is required for Artifact")
+		}
+		// Only the storage location is needed to start the worker.
+		storageRef := restore.Spec.SourceRef.Artifact.StorageRef
+		return &backupv1alpha1.Backup{Spec: backupv1alpha1.BackupSpec{StorageRef: &storageRef}}, nil
 	default:
 		return nil, fmt.Errorf("unsupported sourceRef.kind %q", restore.Spec.SourceRef.Kind)
 	}
@@ -142,5 +160,6 @@ func (r *ClusterRestoreReconciler) failClusterRestore(ctx context.Context, resto
 func (r *ClusterRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&backupv1alpha1.ClusterRestore{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(enqueueOwnerForJob("ClusterRestore", jobTypeRestore))).
 		Complete(r)
 }
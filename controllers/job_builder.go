@@ -20,6 +20,16 @@ const (
 	labelOwnerKind      = "backup.example.com/owner-kind"
 	labelOwnerName      = "backup.example.com/owner-name"
 	labelOwnerNamespace = "backup.example.com/owner-namespace"
+	labelJobType        = "backup.example.com/job-type"
+)
+
+const (
+	jobTypeBackup  = "backup"
+	jobTypeRestore = "restore"
+	jobTypeGC      = "gc"
+	jobTypeCleanup = "cleanup"
+	jobTypeProbe   = "probe"
+	jobTypeCatalog = "catalog"
 )
 
 func operatorNamespace() string {
@@ -50,6 +60,9 @@ func clusterID() string {
 	return "cluster-unknown"
 }
 
+// workerTuningEnv lists manager environment variables that are passed through to worker Jobs.
+var workerTuningEnv = []string{"WORKER_KUBE_API_QPS", "WORKER_KUBE_API_BURST", "WORKER_LIST_CONCURRENCY"}
+
 func buildOwnerLabels(kind, name, namespace string, uid types.UID) map[string]string {
 	labels := map[string]string{
 		labelOwnerUID:  string(uid),
@@ -62,105 +75,84 @@ func buildOwnerLabels(kind, name, namespace string, uid types.UID) map[string]st
 	return labels
 }
 
-func findJob(ctx context.Context, c client.Client, kind, name, namespace string, uid types.UID) (*batchv1.Job, error) {
+// findJob selects on the owner labels only, not the job type, so Jobs created before the job-type label existed
+// are still found.
+func findJob(ctx context.Context, c client.Client, jobType, kind, name, namespace string, uid types.UID) (*batchv1.Job, error) {
 	jobList := &batchv1.JobList{}
-	match := client.MatchingLabels{labelOwnerUID: string(uid)}
+	match := client.MatchingLabelsSelector{Selector: labelSelectorForOwner(kind, name, namespace, uid)}
 	if err := c.List(ctx, jobList, match, client.InNamespace(operatorNamespace())); err != nil {
 		return nil, err
 	}
 	for i := range jobList.Items {
 		job := &jobList.Items[i]
-		if job.Labels[labelOwnerKind] == kind && job.Labels[labelOwnerName] == name && job.Labels[labelOwnerNamespace] == namespace {
+		// Cluster-scoped owners carry no namespace label, which the selector cannot express.
+		if job.Labels[labelOwnerNamespace] == namespace && workerJobType(job) == jobType {
 			return job, nil
 		}
 	}
 	return nil, nil
 }
 
-func buildBackupJob(ownerKind, ownerName, ownerNamespace string, ownerUID types.UID, storage *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
-	jobName := strings.ToLower(fmt.Sprintf("backup-%s-", ownerName))
-	labels := buildOwnerLabels(ownerKind, ownerName, ownerNamespace, ownerUID)
-
-	container := corev1.Container{
-		Name:    "backup-worker",
-		Image:   operatorImage(),
-		Command: []string{"/manager"},
-		Args: []string{
-			"--mode=backup-worker",
-			fmt.Sprintf("--worker-kind=%s", ownerKind),
-			fmt.Sprintf("--worker-name=%s", ownerName),
-			fmt.Sprintf("--worker-namespace=%s", ownerNamespace),
-		},
+// workerJobType reads the job-type label, or for Jobs created before it existed derives the type from the worker
+// mode, which is always the job type plus "-worker".
+func workerJobType(job *batchv1.Job) string {
+	if jobType, ok := job.Labels[labelJobType]; ok {
+		return jobType
+	}
+	for _, container := range job.Spec.Template.Spec.Containers {
+		for _, arg := range container.Args {
+			if mode, ok := strings.CutPrefix(arg, "--mode="); ok {
+				return strings.TrimSuffix(mode, "-worker")
+			}
+		}
 	}
-	container.Env = append(container.Env,
-		corev1.EnvVar{
-			Name:  "CLUSTER_ID",
-			Value: clusterID(),
-		},
-		corev1.EnvVar{
-			Name: "POD_NAMESPACE",
-			ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
-			},
-		},
-	)
+	return ""
+}
 
-	podSpec := corev1.PodSpec{
-		ServiceAccountName: operatorServiceAccount(),
-		RestartPolicy:      corev1.RestartPolicyNever,
-		Containers:         []corev1.Container{container},
-	}
+func buildBackupJob(ownerKind, ownerName, ownerNamespace string, ownerUID types.UID, storage *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
+	return buildWorkerJob(jobTypeBackup, "backup-worker", ownerKind, ownerName, ownerNamespace, ownerUID, storage)
+}
 
-	if storage != nil && storage.Spec.Type == backupv1alpha1.StorageLocationNFS {
-		if storage.Spec.NFS == nil || storage.Spec.NFS.Server == "" || storage.Spec.NFS.Path == "" {
-			return nil, fmt.Errorf("nfs storage location missing server/path")
-		}
+func buildRestoreJob(ownerKind, ownerName, ownerNamespace string, ownerUID types.UID, storage *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
+	return buildWorkerJob(jobTypeRestore, "restore-worker", ownerKind, ownerName, ownerNamespace, ownerUID, storage)
+}
 
-		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
-			Name: "nfs-storage",
-			VolumeSource: corev1.VolumeSource{
-				NFS: &corev1.NFSVolumeSource{
-					Server:   storage.Spec.NFS.Server,
-					Path:     storage.Spec.NFS.Path,
-					ReadOnly: storage.Spec.NFS.ReadOnly,
-				},
-			},
-		})
-		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
-			Name:      "nfs-storage",
-			MountPath: "/data",
-		})
-		container.Env = append(container.Env, corev1.EnvVar{Name: "NFS_MOUNT_PATH", Value: "/data"})
-	}
+func buildGCJob(ownerKind, ownerName, ownerNamespace string, ownerUID types.UID, storage *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
+	return buildWorkerJob(jobTypeGC, "gc-worker", ownerKind, ownerName, ownerNamespace, ownerUID, storage)
+}
 
-	job := &batchv1.Job{
-		ObjectMeta: metav1.ObjectMeta{
-			GenerateName: jobName,
-			Namespace:    operatorNamespace(),
-			Labels:       labels,
-		},
-		Spec: batchv1.JobSpec{
-			BackoffLimit:            int32Ptr(0),
-			TTLSecondsAfterFinished: int32Ptr(3600),
-			Template: corev1.PodTemplateSpec{
-				Spec: podSpec,
-			},
-		},
-	}
+func buildCleanupJob(ownerKind, ownerName, ownerNamespace string, ownerUID types.UID, storage *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
+	return buildWorkerJob(jobTypeCleanup, "cleanup-worker", ownerKind, ownerName, ownerNamespace, ownerUID, storage)
+}
 
+// buildProbeJob validates a storage location from a worker pod that mounts it.
+func buildProbeJob(location *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
+	job, err := buildWorkerJob(jobTypeProbe, "probe-worker", "BackupStorageLocation", location.Name, "", location.UID, location)
+	if err != nil {
+		return nil, err
+	}
+	deadline := probeJobDeadline
+	job.Spec.ActiveDeadlineSeconds = &deadline
 	return job, nil
 }
 
-func buildRestoreJob(ownerKind, ownerName, ownerNamespace string, ownerUID types.UID, storage *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
-	jobName := strings.ToLower(fmt.Sprintf("restore-%s-", ownerName))
+// buildCatalogJob syncs the backups found in a storage location into the cluster.
+func buildCatalogJob(location *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
+	return buildWorkerJob(jobTypeCatalog, "catalog-worker", "BackupStorageLocation", location.Name, "", location.UID, location)
+}
+
+func buildWorkerJob(jobType, mode, ownerKind, ownerName, ownerNamespace string, ownerUID types.UID, storage *backupv1alpha1.BackupStorageLocation) (*batchv1.Job, error) {
+	jobName := strings.ToLower(fmt.Sprintf("%s-%s-", jobType, ownerName))
 	labels := buildOwnerLabels(ownerKind, ownerName, ownerNamespace, ownerUID)
+	labels[labelJobType] = jobType
 
 	container := corev1.Container{
-		Name:    "restore-worker",
-		Image:   operatorImage(),
-		Command: []string{"/manager"},
+		Name:                     mode,
+		Image:                    operatorImage(),
+		Command:                  []string{"/manager"},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 		Args: []string{
-			"--mode=restore-worker",
+			fmt.Sprintf("--mode=%s", mode),
 			fmt.Sprintf("--worker-kind=%s", ownerKind),
 			fmt.Sprintf("--worker-name=%s", ownerName),
 			fmt.Sprintf("--worker-namespace=%s", ownerNamespace),
@@ -178,34 +170,31 @@ func buildRestoreJob(ownerKind, ownerName, ownerNamespace string, ownerUID types
 			},
 		},
 	)
+	for _, name := range workerTuningEnv {
+		if value := os.Getenv(name); value != "" {
+			container.Env = append(container.Env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
 
 	podSpec := corev1.PodSpec{
 		ServiceAccountName: operatorServiceAccount(),
 		RestartPolicy:      corev1.RestartPolicyNever,
-		Containers:         []corev1.Container{container},
 	}
 
 	if storage != nil && storage.Spec.Type == backupv1alpha1.StorageLocationNFS {
-		if storage.Spec.NFS == nil || storage.Spec.NFS.Server == "" || storage.Spec.NFS.Path == "" {
-			return nil, fmt.Errorf("nfs storage location missing server/path")
+		volume, subPath, err := nfsWorkerVolume(storage)
+		if err != nil {
+			return nil, err
 		}
-
-		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
-			Name: "nfs-storage",
-			VolumeSource: corev1.VolumeSource{
-				NFS: &corev1.NFSVolumeSource{
-					Server:   storage.Spec.NFS.Server,
-					Path:     storage.Spec.NFS.Path,
-					ReadOnly: storage.Spec.NFS.ReadOnly,
-				},
-			},
-		})
+		podSpec.Volumes = append(podSpec.Volumes, volume)
 		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
-			Name:      "nfs-storage",
+			Name:      volume.Name,
 			MountPath: "/data",
+			SubPath:   subPath,
 		})
 		container.Env = append(container.Env, corev1.EnvVar{Name: "NFS_MOUNT_PATH", Value: "/data"})
 	}
+	podSpec.Containers = []corev1.Container{container}
 
 	job := &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
@@ -230,6 +219,5 @@ func int32Ptr(val int32) *int32 {
 }
 
 func labelSelectorForOwner(kind, name, namespace string, uid types.UID) labels.Selector {
-	set := labels.Set(buildOwnerLabels(kind, name, namespace, uid))
-	return set.AsSelector()
+	return labels.Set(buildOwnerLabels(kind, name, namespace, uid)).AsSelector()
 }
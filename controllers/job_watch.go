@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// enqueueOwnerForJob maps worker Job events back to the resource that created the Job.
+func enqueueOwnerForJob(kind string, jobTypes ...string) handler.MapFunc {
+	return func(_ context.Context, obj client.Object) []reconcile.Request {
+		if obj.GetNamespace() != operatorNamespace() {
+			return nil
+		}
+		job, ok := obj.(*batchv1.Job)
+		if !ok {
+			return nil
+		}
+		jobLabels := job.GetLabels()
+		if jobLabels[labelOwnerKind] != kind || !slices.Contains(jobTypes, workerJobType(job)) || jobLabels[labelOwnerName] == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{
+			Name:      jobLabels[labelOwnerName],
+			Namespace: jobLabels[labelOwnerNamespace],
+		}}}
+	}
+}
+
+// jobFailureMessage returns the termination message of the failed worker pod, falling back to the
+// Job's failure condition and then to fallback.
+func jobFailureMessage(ctx context.Context, c client.Client, job *batchv1.Job, fallback string) string {
+	if job.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+		if err == nil {
+			var pods corev1.PodList
+			if err := c.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabelsSelector{Selector: selector}); err == nil {
+				for i := range pods.Items {
+					for _, status := range pods.Items[i].Status.ContainerStatuses {
+						terminated := status.State.Terminated
+						if terminated == nil || terminated.ExitCode == 0 {
+							continue
+						}
+						if message := strings.TrimSpace(terminated.Message); message != "" {
+							return message
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue && condition.Message != "" {
+			return fallback + ": " + condition.Message
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// nfsCSIDriver mounts exports that need credentials, which the in-tree NFS volume cannot pass to the mount.
+const nfsCSIDriver = "nfs.csi.k8s.io"
+
+// errNFSVolumeReplacing is returned while the volume and claim of a location whose export changed are deleted.
+var errNFSVolumeReplacing = errors.New("waiting for the PersistentVolume of the previous export to be deleted")
+
+// nfsVolumeCapacity is only nominal; NFS does not enforce it, but binding a PersistentVolume requires one.
+var nfsVolumeCapacity = resource.MustParse("1Gi")
+
+// nfsNeedsVolume reports whether an NFS location given by server and path needs a PersistentVolume provisioned by
+// the operator, because a pod's inline NFS volume supports neither mount options nor credentials.
+func nfsNeedsVolume(nfs *backupv1alpha1.NFSLocationSpec) bool {
+	return nfs.PVCRef == nil && (len(nfs.MountOptions) > 0 || nfs.CredentialsSecretRef != nil)
+}
+
+// nfsVolumeNames returns the names of the PersistentVolume and claim provisioned for a location.
+func nfsVolumeNames(location *backupv1alpha1.BackupStorageLocation) (string, string) {
+	return "backup-operator-bsl-" + location.Name, "bsl-" + location.Name
+}
+
+// nfsWorkerVolume returns the volume worker Jobs mount for an NFS location, and the sub-path of it to mount.
+func nfsWorkerVolume(location *backupv1alpha1.BackupStorageLocation) (corev1.Volume, string, error) {
+	nfs := location.Spec.NFS
+	volume := corev1.Volume{Name: "nfs-storage"}
+	switch {
+	case nfs == nil:
+		return volume, "", fmt.Errorf("nfs storage location missing nfs settings")
+	case nfs.PVCRef != nil:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: nfs.PVCRef.Name, ReadOnly: nfs.ReadOnly}
+		return volume, strings.Trim(nfs.Path, "/"), nil
+	case nfs.Server == "" || nfs.Path == "":
+		return volume, "", fmt.Errorf("nfs storage location missing server/path")
+	case nfsNeedsVolume(nfs):
+		_, claimName := nfsVolumeNames(location)
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName, ReadOnly: nfs.ReadOnly}
+		return volume, "", nil
+	default:
+		volume.NFS = &corev1.NFSVolumeSource{Server: nfs.Server, Path: nfs.Path, ReadOnly: nfs.ReadOnly}
+		return volume, "", nil
+	}
+}
+
+// ensureNFSVolume provisions a PersistentVolume for the export and a claim bound to it in the operator's namespace.
+// Both are owned by the location and deleted with it; the volume's data is retained.
+func ensureNFSVolume(ctx context.Context, c client.Client, scheme *runtime.Scheme, location *backupv1alpha1.BackupStorageLocation) error {
+	nfs := location.Spec.NFS
+	volumeName, claimName := nfsVolumeNames(location)
+
+	volume := &corev1.PersistentVolume{}
+	err := c.Get(ctx, types.NamespacedName{Name: volumeName}, volume)
+	switch {
+	case apierrors.IsNotFound(err):
+		volume = &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: volumeName},
+			Spec: corev1.PersistentVolumeSpec{
+				Capacity:                      corev1.ResourceList{corev1.ResourceStorage: nfsVolumeCapacity},
+				AccessModes:                   []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+				PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+				MountOptions:                  nfs.MountOptions,
+				ClaimRef:                      &corev1.ObjectReference{Namespace: operatorNamespace(), Name: claimName},
+				PersistentVolumeSource:        nfsVolumeSource(location),
+			},
+		}
+		if err := controllerutil.SetControllerReference(location, volume, scheme); err != nil {
+			return err
+		}
+		if err := c.Create(ctx, volume); err != nil {
+			return fmt.Errorf("create PersistentVolume %s: %w", volumeName, err)
+		}
+	case err != nil:
+		return err
+	case volume.DeletionTimestamp != nil:
+		return errNFSVolumeReplacing
+	case !equality.Semantic.DeepEqual(volume.Spec.PersistentVolumeSource, nfsVolumeSource(location)):
+		// The volume source is immutable, so a changed server, path or credentials Secret needs a new volume and
+		// claim. Both are only gone once no worker mounts them any more.
+		return replaceNFSVolume(ctx, c, volume, claimName)
+	default:
+		// Mount options are picked up by the next mount.
+		if strings.Join(volume.Spec.MountOptions, ",") != strings.Join(nfs.MountOptions, ",") {
+			volume.Spec.MountOptions = nfs.MountOptions
+			if err := c.Update(ctx, volume); err != nil {
+				return fmt.Errorf("update PersistentVolume %s: %w", volumeName, err)
+			}
+		}
+	}
+
+	claim := &corev1.PersistentVolumeClaim{}
+	err = c.Get(ctx, types.NamespacedName{Name: claimName, Namespace: operatorNamespace()}, claim)
+	if err == nil && claim.DeletionTimestamp != nil {
+		return errNFSVolumeReplacing
+	}
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	storageClass := ""
+	claim = &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: claimName, Namespace: operatorNamespace()},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			StorageClassName: &storageClass,
+			VolumeName:       volumeName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: nfsVolumeCapacity},
+			},
+		},
+	}
+	if err := controllerutil.SetControllerReference(location, claim, scheme); err != nil {
+		return err
+	}
+	if err := c.Create(ctx, claim); err != nil {
+		return fmt.Errorf("create PersistentVolumeClaim %s: %w", claimName, err)
+	}
+	return nil
+}
+
+// replaceNFSVolume deletes the claim and volume of an export that changed. The next reconcile creates them anew.
+func replaceNFSVolume(ctx context.Context, c client.Client, volume *corev1.PersistentVolume, claimName string) error {
+	claim := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: claimName, Namespace: operatorNamespace()}}
+	if err := c.Delete(ctx, claim); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("delete PersistentVolumeClaim %s: %w", claimName, err)
+	}
+	if err := c.Delete(ctx, volume); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("delete PersistentVolume %s: %w", volume.Name, err)
+	}
+	return errNFSVolumeReplacing
+}
+
+// nfsVolumeSource uses the in-tree NFS volume unless the export needs credentials, which go through the NFS CSI
+// driver's node-publish secret.
+func nfsVolumeSource(location *backupv1alpha1.BackupStorageLocation) corev1.PersistentVolumeSource {
+	nfs := location.Spec.NFS
+	if nfs.CredentialsSecretRef == nil {
+		return corev1.PersistentVolumeSource{
+			NFS: &corev1.NFSVolumeSource{Server: nfs.Server, Path: nfs.Path, ReadOnly: nfs.ReadOnly},
+		}
+	}
+	secretRef := *nfs.CredentialsSecretRef
+	if secretRef.Namespace == "" {
+		secretRef.Namespace = operatorNamespace()
+	}
+	return corev1.PersistentVolumeSource{
+		CSI: &corev1.CSIPersistentVolumeSource{
+			Driver: nfsCSIDriver,
+			// The handle only has to be unique among the driver's volumes.
+			VolumeHandle:         fmt.Sprintf("%s#%s#bsl-%s", nfs.Server, nfs.Path, location.Name),
+			ReadOnly:             nfs.ReadOnly,
+			VolumeAttributes:     map[string]string{"server": nfs.Server, "share": nfs.Path},
+			NodePublishSecretRef: &secretRef,
+		},
+	}
+}
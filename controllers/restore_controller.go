@@ -7,10 +7,12 @@ import (
 
 	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
 	"example.com/backup-operator/internal/resolve"
+	batchv1 "k8s.io/api/batch/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -30,11 +32,12 @@ func (r *RestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	logger.V(1).Info("reconciling Restore", "name", restore.Name, "namespace", restore.Namespace)
 
-	if !restore.DeletionTimestamp.IsZero() {
+	// Finished resources are left alone so the worker Job being cleaned up does not start a new run.
+	if !restore.DeletionTimestamp.IsZero() || restoreFinished(restore.Status) {
 		return ctrl.Result{}, nil
 	}
 
-	job, err := findJob(ctx, r.Client, "Restore", restore.Name, restore.Namespace, restore.UID)
+	job, err := findJob(ctx, r.Client, jobTypeRestore, "Restore", restore.Name, restore.Namespace, restore.UID)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -44,9 +47,16 @@ func (r *RestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return r.failRestore(ctx, &restore, err.Error())
 	}
 
-	if sourceBackup.Status.ArtifactLocation == "" {
-		logger.V(1).Info("backup artifact location not ready yet", "backup", sourceBackup.Name)
-		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	// Artifact sources are looked up in storage by the worker, which can reach it. A backup records its artifact
+	// as soon as it is uploaded, so it is only restored from once it has finished without failing.
+	if restore.Spec.SourceRef.Kind != backupv1alpha1.RestoreSourceArtifact {
+		if sourceBackup.Status.Phase == backupv1alpha1.BackupPhaseFailed {
+			return r.failRestore(ctx, &restore, fmt.Sprintf("backup %s failed", sourceBackup.Name))
+		}
+		if sourceBackup.Status.ArtifactLocation == "" || !backupFinished(sourceBackup.Status) {
+			logger.V(1).Info("backup artifact not ready yet", "backup", sourceBackup.Name)
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
 	}
 
 	if job == nil {
@@ -85,7 +95,8 @@ func (r *RestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
-	if job.Status.Succeeded > 0 && restore.Status.Phase != backupv1alpha1.RestorePhaseCompleted {
+	// The worker records Completed or Failed itself; only fill in a phase it never got to write.
+	if job.Status.Succeeded > 0 && !restoreFinished(restore.Status) {
 		restore.Status.Phase = backupv1alpha1.RestorePhaseCompleted
 		restore.Status.ObservedGeneration = restore.Generation
 		if err := r.Status().Update(ctx, &restore); err != nil {
@@ -93,8 +104,8 @@ func (r *RestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
-	if job.Status.Failed > 0 && restore.Status.Phase != backupv1alpha1.RestorePhaseFailed {
-		return r.failRestore(ctx, &restore, "restore job failed")
+	if job.Status.Failed > 0 && !restoreFinished(restore.Status) {
+		return r.failRestore(ctx, &restore, jobFailureMessage(ctx, r.Client, job, "restore job failed"))
 	}
 
 	return ctrl.Result{}, nil
@@ -123,6 +134,13 @@ func (r *RestoreReconciler) getBackupForRestore(ctx context.Context, restore *ba
 			Status:     clusterBackup.Status.BackupStatus,
 		}
 		return &converted, nil
+	case backupv1alpha1.RestoreSourceArtifact:
+		if restore.Spec.SourceRef.Artifact == nil {
+			return nil, fmt.Errorf("sourceRef.artifact is required for Artifact")
+		}
+		// Only the storage location is needed to start the worker.
+		storageRef := restore.Spec.SourceRef.Artifact.StorageRef
+		return &backupv1alpha1.Backup{Spec: backupv1alpha1.BackupSpec{StorageRef: &storageRef}}, nil
 	default:
 		return nil, fmt.Errorf("unsupported sourceRef.kind %q", restore.Spec.SourceRef.Kind)
 	}
@@ -140,8 +158,18 @@ func (r *RestoreReconciler) failRestore(ctx context.Context, restore *backupv1al
 	return ctrl.Result{}, nil
 }
 
+func restoreFinished(status backupv1alpha1.RestoreStatus) bool {
+	switch status.Phase {
+	case backupv1alpha1.RestorePhaseCompleted, backupv1alpha1.RestorePhasePartiallyFailed, backupv1alpha1.RestorePhaseFailed:
+		return true
+	default:
+		return false
+	}
+}
+
 func (r *RestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&backupv1alpha1.Restore{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(enqueueOwnerForJob("Restore", jobTypeRestore))).
 		Complete(r)
 }
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	// Embed the IANA database so ScheduleSpec.TimeZone works in minimal images.
+	_ "time/tzdata"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	labelScheduleName = "backup.example.com/schedule-name"
+
+	defaultStartingDeadlineSeconds int64 = 300
+	scheduleTimestampFormat              = "20060102-150405"
+	// maxCatchUpRuns caps how many missed runs the CatchUp policy starts; older ones are skipped.
+	maxCatchUpRuns = 10
+)
+
+// scheduledRun describes the schedule state at a given instant.
+type scheduledRun struct {
+	// due is the scheduled time to act on now: the most recent one not acted on yet, or with CatchUp the oldest
+	// one within maxCatchUpRuns.
+	due *time.Time
+	// missed reports whether due is older than the starting deadline.
+	missed bool
+	// skipped counts the runs before due that are passed over: all of them unless CatchUp is set, and with CatchUp
+	// those beyond maxCatchUpRuns.
+	skipped int32
+	// pending reports whether CatchUp has more runs due after this one.
+	pending bool
+	next    time.Time
+}
+
+func parseSchedule(spec backupv1alpha1.ScheduleSpec) (cron.Schedule, error) {
+	timeZone := "UTC"
+	if spec.TimeZone != nil && *spec.TimeZone != "" {
+		timeZone = *spec.TimeZone
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", timeZone, err)
+	}
+	sched, err := cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", timeZone, spec.Schedule))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec.Schedule, err)
+	}
+	return sched, nil
+}
+
+func nextScheduledRun(spec backupv1alpha1.ScheduleSpec, status backupv1alpha1.ScheduleStatus, created, now time.Time) (scheduledRun, error) {
+	sched, err := parseSchedule(spec)
+	if err != nil {
+		return scheduledRun{}, err
+	}
+
+	earliest := created
+	if status.LastScheduleTime != nil {
+		earliest = status.LastScheduleTime.Time
+	}
+
+	run := scheduledRun{next: sched.Next(now)}
+	var slots []time.Time
+	total := 0
+	for t := sched.Next(earliest); !t.After(now); t = sched.Next(t) {
+		total++
+		slots = append(slots, t)
+		if len(slots) > maxCatchUpRuns {
+			slots = slots[1:]
+		}
+	}
+	switch {
+	case len(slots) == 0:
+	case spec.MissedRunPolicy == backupv1alpha1.ScheduleMissedRunCatchUp:
+		run.due = &slots[0]
+		run.skipped = int32(total - len(slots))
+		run.pending = len(slots) > 1
+	default:
+		run.due = &slots[len(slots)-1]
+		run.skipped = int32(total - 1)
+	}
+	if run.due != nil {
+		deadline := defaultStartingDeadlineSeconds
+		if spec.StartingDeadlineSeconds != nil {
+			deadline = *spec.StartingDeadlineSeconds
+		}
+		run.missed = now.Sub(*run.due) > time.Duration(deadline)*time.Second
+	}
+	return run, nil
+}
+
+func scheduleSuspended(spec backupv1alpha1.ScheduleSpec) bool {
+	return spec.Suspend != nil && *spec.Suspend
+}
+
+func scheduledBackupName(scheduleName string, scheduled time.Time) string {
+	suffix := scheduled.UTC().Format(scheduleTimestampFormat)
+	maxPrefix := 63 - len(suffix) - 1
+	if len(scheduleName) > maxPrefix {
+		scheduleName = scheduleName[:maxPrefix]
+	}
+	return fmt.Sprintf("%s-%s", scheduleName, suffix)
+}
+
+// observeScheduledBackup folds a child backup into the schedule status and reports whether it is still active.
+func observeScheduledBackup(status *backupv1alpha1.ScheduleStatus, name string, backupStatus backupv1alpha1.BackupStatus) bool {
+	switch backupStatus.Phase {
+	case backupv1alpha1.BackupPhaseCompleted:
+		if backupStatus.CompletedAt != nil &&
+			(status.LastSuccessfulTime == nil || backupStatus.CompletedAt.After(status.LastSuccessfulTime.Time)) {
+			status.LastSuccessfulBackup = name
+			completedAt := *backupStatus.CompletedAt
+			status.LastSuccessfulTime = &completedAt
+		}
+		return false
+	case backupv1alpha1.BackupPhasePartiallyFailed, backupv1alpha1.BackupPhaseFailed:
+		return false
+	default:
+		status.Active = append(status.Active, name)
+		return true
+	}
+}
+
+func requeueForSchedule(run scheduledRun, now time.Time) reconcile.Result {
+	wait := run.next.Sub(now)
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return reconcile.Result{RequeueAfter: wait}
+}
+
+// scheduleTarget is what reconcileSchedule needs to know about a BackupSchedule or ClusterBackupSchedule.
+type scheduleTarget struct {
+	// kind is the kind of backup the schedule creates.
+	kind   string
+	spec   backupv1alpha1.ScheduleSpec
+	status *backupv1alpha1.ScheduleStatus
+	// list returns the backups labelled with the schedule's name.
+	list func(ctx context.Context) ([]scheduledChild, error)
+	// newBackup returns the backup to create for a run.
+	newBackup func(objectMeta metav1.ObjectMeta) client.Object
+}
+
+type scheduledChild struct {
+	obj    client.Object
+	status backupv1alpha1.BackupStatus
+}
+
+// reconcileSchedule creates the backup of a schedule's due run and records the schedule's state in its status.
+// Only backups the schedule controls count as its runs; the schedule-name label alone can be set by anyone.
+func reconcileSchedule(ctx context.Context, c client.Client, scheme *runtime.Scheme, schedule client.Object, target scheduleTarget) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("reconciling "+target.kind+"Schedule", "name", schedule.GetName(), "namespace", schedule.GetNamespace())
+
+	if !schedule.GetDeletionTimestamp().IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	children, err := target.list(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := *target.status
+	status.ObservedGeneration = schedule.GetGeneration()
+	status.Active = nil
+	var active []client.Object
+	for _, child := range children {
+		if !metav1.IsControlledBy(child.obj, schedule) {
+			continue
+		}
+		if observeScheduledBackup(&status, child.obj.GetName(), child.status) {
+			active = append(active, child.obj)
+		}
+	}
+	updateStatus := func(result ctrl.Result) (ctrl.Result, error) {
+		*target.status = status
+		if err := c.Status().Update(ctx, schedule); err != nil {
+			return ctrl.Result{}, err
+		}
+		return result, nil
+	}
+
+	now := time.Now()
+	run, err := nextScheduledRun(target.spec, status, schedule.GetCreationTimestamp().Time, now)
+	if err != nil {
+		status.NextScheduleTime = nil
+		status.Message = err.Error()
+		return updateStatus(ctrl.Result{})
+	}
+	status.NextScheduleTime = &metav1.Time{Time: run.next}
+	result := requeueForSchedule(run, now)
+
+	if scheduleSuspended(target.spec) {
+		status.Message = "schedule suspended"
+		return updateStatus(result)
+	}
+	if run.due == nil {
+		return updateStatus(result)
+	}
+
+	if run.missed && target.spec.MissedRunPolicy != backupv1alpha1.ScheduleMissedRunCatchUp {
+		status.MissedRuns += 1 + run.skipped
+		status.LastScheduleTime = &metav1.Time{Time: *run.due}
+		status.Message = fmt.Sprintf("skipped missed run scheduled at %s", run.due.UTC().Format(time.RFC3339))
+		if run.skipped > 0 {
+			status.Message += fmt.Sprintf(" and %d older missed runs", run.skipped)
+		}
+		return updateStatus(result)
+	}
+
+	if len(active) > 0 {
+		switch target.spec.ConcurrencyPolicy {
+		case backupv1alpha1.ScheduleConcurrencyAllow:
+		case backupv1alpha1.ScheduleConcurrencyReplace:
+			for _, child := range active {
+				if err := c.Delete(ctx, child, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			status.Active = nil
+		default:
+			status.Message = fmt.Sprintf("run scheduled at %s waiting for active backups: %s",
+				run.due.UTC().Format(time.RFC3339), strings.Join(status.Active, ", "))
+			return updateStatus(result)
+		}
+	}
+
+	backup := target.newBackup(metav1.ObjectMeta{
+		Name:      scheduledBackupName(schedule.GetName(), *run.due),
+		Namespace: schedule.GetNamespace(),
+		Labels:    map[string]string{labelScheduleName: schedule.GetName()},
+	})
+	if err := controllerutil.SetControllerReference(schedule, backup, scheme); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := c.Create(ctx, backup); err != nil && !errors.IsAlreadyExists(err) {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("created scheduled "+target.kind, "backup", backup.GetName(), "scheduledAt", run.due)
+	status.LastScheduleTime = &metav1.Time{Time: *run.due}
+	status.Active = append(status.Active, backup.GetName())
+	status.Message = fmt.Sprintf("created %s %s", target.kind, backup.GetName())
+	if run.skipped > 0 {
+		status.MissedRuns += run.skipped
+		status.Message += fmt.Sprintf("; skipped %d older missed runs", run.skipped)
+	}
+	if run.pending {
+		// Catch up with the next missed run; concurrencyPolicy still applies to it.
+		result = ctrl.Result{RequeueAfter: time.Second}
+	}
+	return updateStatus(result)
+}
@@ -0,0 +1,139 @@
+// Package signing creates and checks the detached signatures stored next to backup artifacts.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+)
+
+// Supported signature algorithms. ECDSA signatures are computed over the SHA-256 of the message.
+const (
+	AlgorithmEd25519     = "ed25519"
+	AlgorithmECDSASHA256 = "ecdsa-sha256"
+)
+
+// Signature is a detached signature of an artifact manifest.
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	// KeyFingerprint identifies the public key that verifies the signature.
+	KeyFingerprint string `json:"keyFingerprint"`
+	Value          []byte `json:"value"`
+}
+
+// ParsePrivateKey decodes a PEM-encoded ed25519 or ECDSA private key in PKCS#8 or, for ECDSA, SEC 1 form.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T; use ed25519 or ECDSA", key)
+	}
+}
+
+// ParsePublicKey decodes a PEM-encoded PKIX ed25519 or ECDSA public key.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T; use ed25519 or ECDSA", key)
+	}
+}
+
+// Fingerprint returns the SHA-256 of the key's PKIX encoding as "sha256:<hex>".
+func Fingerprint(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// Sign signs message with key.
+func Sign(key crypto.Signer, message []byte) (*Signature, error) {
+	fingerprint, err := Fingerprint(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	signature := &Signature{KeyFingerprint: fingerprint}
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		signature.Algorithm = AlgorithmEd25519
+		signature.Value = ed25519.Sign(key, message)
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(message)
+		signature.Algorithm = AlgorithmECDSASHA256
+		if signature.Value, err = ecdsa.SignASN1(rand.Reader, key, digest[:]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signature, nil
+}
+
+// Verify checks signature over message against the trusted keys and returns the fingerprint of the key that
+// verified it.
+func Verify(trusted []crypto.PublicKey, message []byte, signature *Signature) (string, error) {
+	for _, key := range trusted {
+		fingerprint, err := Fingerprint(key)
+		if err != nil || fingerprint != signature.KeyFingerprint {
+			continue
+		}
+		if !verifyWith(key, message, signature) {
+			return "", fmt.Errorf("signature by key %s does not match the manifest", fingerprint)
+		}
+		return fingerprint, nil
+	}
+	return "", fmt.Errorf("signature was made with key %s, which is not trusted", signature.KeyFingerprint)
+}
+
+func verifyWith(key crypto.PublicKey, message []byte, signature *Signature) bool {
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		return signature.Algorithm == AlgorithmEd25519 && ed25519.Verify(key, message, signature.Value)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return signature.Algorithm == AlgorithmECDSASHA256 && ecdsa.VerifyASN1(key, digest[:], signature.Value)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// azureBlockSize bounds how much of a blob is held in memory during upload. A block blob holds at most 50,000
+// blocks, so this allows blobs of roughly 390 GiB.
+const azureBlockSize = 8 << 20
+
+type azureBackend struct {
+	client    *container.Client
+	account   string
+	container string
+	prefix    string
+	sas       bool
+}
+
+func validateAzure(location *backupv1alpha1.BackupStorageLocation) error {
+	if location.Spec.Azure == nil || location.Spec.Azure.Account == "" || location.Spec.Azure.Container == "" {
+		return fmt.Errorf("azure storage location requires account and container")
+	}
+	if location.Spec.Azure.SecretRef.Name == "" {
+		return fmt.Errorf("azure storage location requires secretRef.name, a Secret with accountKey or sasToken")
+	}
+	return nil
+}
+
+func newAzureBackend(ctx context.Context, c client.Reader, location *backupv1alpha1.BackupStorageLocation) (Backend, error) {
+	spec := location.Spec.Azure
+	endpoint := strings.TrimRight(spec.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", spec.Account)
+	}
+	containerURL := endpoint + "/" + spec.Container
+
+	var accountKey, sasToken string
+	if spec.SecretRef.Name != "" {
+		secret, err := readSecret(ctx, c, spec.SecretRef)
+		if err != nil {
+			return nil, err
+		}
+		accountKey = string(secret.Data["accountKey"])
+		sasToken = strings.TrimPrefix(string(secret.Data["sasToken"]), "?")
+	}
+
+	var containerClient *container.Client
+	switch {
+	case accountKey != "":
+		cred, err := azblob.NewSharedKeyCredential(spec.Account, accountKey)
+		if err != nil {
+			return nil, fmt.Errorf("azure shared key: %w", err)
+		}
+		containerClient, err = container.NewClientWithSharedKeyCredential(containerURL, cred, nil)
+		if err != nil {
+			return nil, err
+		}
+	case sasToken != "":
+		var err error
+		containerClient, err = container.NewClientWithNoCredential(containerURL+"?"+sasToken, nil)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("azure storage location requires a Secret with accountKey or sasToken")
+	}
+
+	return &azureBackend{
+		client:    containerClient,
+		account:   spec.Account,
+		container: spec.Container,
+		prefix:    strings.Trim(spec.Prefix, "/"),
+		sas:       accountKey == "",
+	}, nil
+}
+
+func (b *azureBackend) blobName(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+	return b.prefix + "/" + key
+}
+
+// Put stages write's output as blocks and commits the block list once write returns. Staged blocks that are never
+// committed are discarded by the service.
+func (b *azureBackend) Put(ctx context.Context, key string, write func(io.Writer) error) (int64, error) {
+	blocks := &azureBlockWriter{ctx: ctx, client: b.client.NewBlockBlobClient(b.blobName(key)),
+		buf: make([]byte, 0, azureBlockSize)}
+	err := write(blocks)
+	if err == nil {
+		err = blocks.flush()
+	}
+	if err == nil {
+		_, err = blocks.client.CommitBlockList(ctx, blocks.ids, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return blocks.size, nil
+}
+
+// azureBlockWriter buffers one block at a time and stages it as soon as it is full.
+type azureBlockWriter struct {
+	ctx    context.Context
+	client *blockblob.Client
+	buf    []byte
+	ids    []string
+	size   int64
+}
+
+func (w *azureBlockWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		w.size += int64(n)
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush stages the buffered bytes as the next block. Block IDs must all have the same length, so they are
+// zero-padded.
+func (w *azureBlockWriter) flush() error {
+	if len(w.buf) == 0 && len(w.ids) > 0 {
+		return nil
+	}
+	number := len(w.ids) + 1
+	id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", number)))
+	if _, err := w.client.StageBlock(w.ctx, id, streaming.NopCloser(bytes.NewReader(w.buf)), nil); err != nil {
+		return fmt.Errorf("stage block %d: %w", number, err)
+	}
+	w.ids = append(w.ids, id)
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Get downloads the blob. The SDK retries interrupted reads of the body from the last received offset.
+func (b *azureBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	blobClient := b.client.NewBlobClient(b.blobName(key))
+	resp, err := blobClient.DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, azureError(key, err)
+	}
+	return resp.NewRetryReader(ctx, &blob.RetryReaderOptions{MaxRetries: 3}), nil
+}
+
+func (b *azureBackend) Stat(ctx context.Context, key string) (*Object, error) {
+	resp, err := b.client.NewBlobClient(b.blobName(key)).GetProperties(ctx, nil)
+	if err != nil {
+		return nil, azureError(key, err)
+	}
+	return &Object{Key: key, Size: valueOf(resp.ContentLength), ModTime: valueOf(resp.LastModified)}, nil
+}
+
+func (b *azureBackend) List(ctx context.Context, prefix string, visit func(Object) error) error {
+	pager := b.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{Prefix: to.Ptr(b.blobName(prefix))})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Segment.BlobItems {
+			key := valueOf(item.Name)
+			if b.prefix != "" {
+				key = strings.TrimPrefix(key, b.prefix+"/")
+			}
+			object := Object{Key: key}
+			if item.Properties != nil {
+				object.Size = valueOf(item.Properties.ContentLength)
+				object.ModTime = valueOf(item.Properties.LastModified)
+			}
+			if err := visit(object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *azureBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.NewBlobClient(b.blobName(key)).Delete(ctx, nil)
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (b *azureBackend) Location(key string) string {
+	return fmt.Sprintf("azure://%s/%s/%s", b.account, b.container, b.blobName(key))
+}
+
+func (b *azureBackend) Key(location string) (string, error) {
+	trimmed, ok := strings.CutPrefix(location, fmt.Sprintf("azure://%s/%s/", b.account, b.container))
+	if !ok {
+		return "", fmt.Errorf("azure location %q is not in container %s/%s", location, b.account, b.container)
+	}
+	if b.prefix == "" {
+		return trimmed, nil
+	}
+	key, ok := strings.CutPrefix(trimmed, b.prefix+"/")
+	if !ok {
+		return "", fmt.Errorf("azure location %q is not under prefix %s", location, b.prefix)
+	}
+	return key, nil
+}
+
+// Check fails unless the container exists and the credentials can access it. SAS tokens scoped to the container
+// cannot read its properties, so they are left to the sentinel write.
+func (b *azureBackend) Check(ctx context.Context) error {
+	if b.sas {
+		return nil
+	}
+	if _, err := b.client.GetProperties(ctx, nil); err != nil {
+		return fmt.Errorf("get container %s: %w", b.container, err)
+	}
+	return nil
+}
+
+// azureError maps the service's not-found errors to ErrNotFound.
+func azureError(key string, err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return err
+}
+
+// valueOf dereferences the optional fields of SDK responses.
+func valueOf[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// partialSuffix marks files that are still being written.
+const partialSuffix = ".partial"
+
+// dirBackend stores objects as files below a local directory.
+type dirBackend struct {
+	root string
+}
+
+// NewDirectory returns a backend that stores objects as files below root, with file:// locations. It backs the NFS
+// driver and can stand in for any storage type in tests.
+func NewDirectory(root string) Backend {
+	return &dirBackend{root: root}
+}
+
+func (b *dirBackend) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || strings.HasSuffix(key, "/") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(b.root, filepath.FromSlash(cleaned)), nil
+}
+
+// Put streams write's output into a temporary file and renames it into place once it is complete, so a failed run
+// never leaves a truncated object behind.
+func (b *dirBackend) Put(_ context.Context, key string, write func(io.Writer) error) (int64, error) {
+	destPath, err := b.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0700); err != nil {
+		return 0, err
+	}
+
+	tmpPath := destPath + partialSuffix
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+
+	out := &countingWriter{w: file}
+	err = write(out)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, destPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, err
+	}
+	return out.n, nil
+}
+
+func (b *dirBackend) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	filePath, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fileError(key, err)
+	}
+	return file, nil
+}
+
+func (b *dirBackend) Stat(_ context.Context, key string) (*Object, error) {
+	filePath, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fileError(key, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return &Object{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (b *dirBackend) List(ctx context.Context, prefix string, visit func(Object) error) error {
+	// Only walk the directory the prefix points into; the rest of the tree cannot match.
+	start := b.root
+	if dir := path.Dir(prefix); dir != "." && dir != "/" {
+		start = filepath.Join(b.root, filepath.FromSlash(path.Clean("/"+dir)))
+	}
+
+	var objects []Object
+	err := filepath.WalkDir(start, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), partialSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	for _, object := range objects {
+		if err := visit(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes the file and then any directories it leaves empty.
+func (b *dirBackend) Delete(_ context.Context, key string) error {
+	filePath, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	root := filepath.Clean(b.root)
+	for dir := filepath.Dir(filePath); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		// This fails harmlessly as soon as a directory still holds anything else.
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (b *dirBackend) Location(key string) string {
+	return "file://" + path.Join(filepath.ToSlash(b.root), key)
+}
+
+func (b *dirBackend) Key(location string) (string, error) {
+	trimmed, ok := strings.CutPrefix(location, "file://")
+	if !ok {
+		return "", fmt.Errorf("invalid file location %q", location)
+	}
+	key, ok := strings.CutPrefix(trimmed, strings.TrimRight(filepath.ToSlash(b.root), "/")+"/")
+	if !ok {
+		return "", fmt.Errorf("file location %q is not under %s", location, b.root)
+	}
+	return key, nil
+}
+
+// fileError maps missing files to ErrNotFound.
+func fileError(key string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	gcs "cloud.google.com/go/storage"
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// gcsChunkSize is the size of each request of a resumable upload, and bounds how much of an object is held in
+// memory. An interrupted request is retried from the start of its chunk rather than of the object.
+const gcsChunkSize = 16 << 20
+
+type gcsBackend struct {
+	bucket *gcs.BucketHandle
+	name   string
+	prefix string
+}
+
+func validateGCS(location *backupv1alpha1.BackupStorageLocation) error {
+	if location.Spec.GCS == nil || location.Spec.GCS.Bucket == "" {
+		return fmt.Errorf("gcs storage location requires bucket")
+	}
+	return nil
+}
+
+func newGCSBackend(ctx context.Context, c client.Reader, location *backupv1alpha1.BackupStorageLocation) (Backend, error) {
+	spec := location.Spec.GCS
+	var opts []option.ClientOption
+	if spec.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(spec.Endpoint))
+	}
+	if spec.SecretRef.Name != "" {
+		secret, err := readSecret(ctx, c, spec.SecretRef)
+		if err != nil {
+			return nil, err
+		}
+		key := secret.Data["key.json"]
+		if len(key) == 0 {
+			return nil, fmt.Errorf("secret %s has no key.json", spec.SecretRef.Name)
+		}
+		opts = append(opts, option.WithCredentialsJSON(key))
+	} else if spec.Endpoint != "" {
+		// Emulators such as fake-gcs-server accept unauthenticated requests, and there may be no default
+		// credentials to find outside of Google Cloud.
+		opts = append(opts, option.WithoutAuthentication())
+	}
+
+	gcsClient, err := gcs.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsBackend{bucket: gcsClient.Bucket(spec.Bucket), name: spec.Bucket, prefix: strings.Trim(spec.Prefix, "/")}, nil
+}
+
+func (b *gcsBackend) objectName(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+	return b.prefix + "/" + key
+}
+
+// Put streams write's output into a resumable upload. The object is only created when the upload is finalized.
+func (b *gcsBackend) Put(ctx context.Context, key string, write func(io.Writer) error) (int64, error) {
+	// Cancelling the writer's context is the only way to abandon an upload without finalizing it.
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := b.bucket.Object(b.objectName(key)).NewWriter(uploadCtx)
+	w.ChunkSize = gcsChunkSize
+	out := &countingWriter{w: w}
+	if err := write(out); err != nil {
+		cancel()
+		_ = w.Close()
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	return out.n, nil
+}
+
+func (b *gcsBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := b.bucket.Object(b.objectName(key)).NewReader(ctx)
+	if err != nil {
+		return nil, gcsError(key, err)
+	}
+	return r, nil
+}
+
+func (b *gcsBackend) Stat(ctx context.Context, key string) (*Object, error) {
+	attrs, err := b.bucket.Object(b.objectName(key)).Attrs(ctx)
+	if err != nil {
+		return nil, gcsError(key, err)
+	}
+	return &Object{Key: key, Size: attrs.Size, ModTime: attrs.Updated}, nil
+}
+
+func (b *gcsBackend) List(ctx context.Context, prefix string, visit func(Object) error) error {
+	it := b.bucket.Objects(ctx, &gcs.Query{Prefix: b.objectName(prefix)})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		key := attrs.Name
+		if b.prefix != "" {
+			key = strings.TrimPrefix(key, b.prefix+"/")
+		}
+		if err := visit(Object{Key: key, Size: attrs.Size, ModTime: attrs.Updated}); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *gcsBackend) Delete(ctx context.Context, key string) error {
+	err := b.bucket.Object(b.objectName(key)).Delete(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (b *gcsBackend) Location(key string) string {
+	return fmt.Sprintf("gs://%s/%s", b.name, b.objectName(key))
+}
+
+func (b *gcsBackend) Key(location string) (string, error) {
+	trimmed, ok := strings.CutPrefix(location, "gs://")
+	bucket, objectName, found := strings.Cut(trimmed, "/")
+	if !ok || !found {
+		return "", fmt.Errorf("invalid gcs location %q", location)
+	}
+	if bucket != b.name {
+		return "", fmt.Errorf("gcs location %q is not in bucket %s", location, b.name)
+	}
+	if b.prefix == "" {
+		return objectName, nil
+	}
+	key, ok := strings.CutPrefix(objectName, b.prefix+"/")
+	if !ok {
+		return "", fmt.Errorf("gcs location %q is not under prefix %s", location, b.prefix)
+	}
+	return key, nil
+}
+
+// Check fails unless the bucket exists and the credentials can access it.
+func (b *gcsBackend) Check(ctx context.Context) error {
+	if _, err := b.bucket.Attrs(ctx); err != nil {
+		return fmt.Errorf("get bucket %s: %w", b.name, err)
+	}
+	return nil
+}
+
+// gcsError maps the client's not-found errors to ErrNotFound.
+func gcsError(key string, err error) error {
+	if errors.Is(err, gcs.ErrObjectNotExist) || errors.Is(err, gcs.ErrBucketNotExist) {
+		return fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return err
+}
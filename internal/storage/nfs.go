@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nfsBackend reads and writes the NFS export through the worker's mount of it. Locations name the export itself,
+// e.g. nfs://server/export/path/key, or for PVC-backed locations the claim and the path within it, e.g.
+// pvc://claim/path/key.
+type nfsBackend struct {
+	*dirBackend
+	scheme string
+	host   string
+	path   string
+}
+
+func validateNFS(location *backupv1alpha1.BackupStorageLocation) error {
+	nfs := location.Spec.NFS
+	switch {
+	case nfs == nil:
+		return fmt.Errorf("nfs storage location requires server and path, or pvcRef")
+	case nfs.PVCRef != nil:
+		if nfs.PVCRef.Name == "" {
+			return fmt.Errorf("nfs storage location requires pvcRef.name")
+		}
+		// The claim's PersistentVolume or StorageClass decides how the export is mounted.
+		if len(nfs.MountOptions) > 0 {
+			return fmt.Errorf("nfs storage location with pvcRef must not set mountOptions; set them on the " +
+				"PersistentVolume or StorageClass behind the claim")
+		}
+		if nfs.CredentialsSecretRef != nil {
+			return fmt.Errorf("nfs storage location with pvcRef must not set credentialsSecretRef; set credentials " +
+				"on the PersistentVolume or StorageClass behind the claim")
+		}
+	case nfs.Server == "" || nfs.Path == "":
+		return fmt.Errorf("nfs storage location requires server and path, or pvcRef")
+	case nfs.CredentialsSecretRef != nil && nfs.CredentialsSecretRef.Name == "":
+		return fmt.Errorf("nfs storage location requires credentialsSecretRef.name")
+	}
+	return nil
+}
+
+// newNFSBackend relies on worker Jobs mounting the export, or the sub-path Path of the claim, at NFS_MOUNT_PATH.
+func newNFSBackend(_ context.Context, _ client.Reader, location *backupv1alpha1.BackupStorageLocation) (Backend, error) {
+	nfs := location.Spec.NFS
+	backend := &nfsBackend{
+		dirBackend: &dirBackend{root: nfsMountPath()},
+		scheme:     "nfs",
+		host:       nfs.Server,
+		path:       "/" + strings.Trim(nfs.Path, "/"),
+	}
+	if nfs.PVCRef != nil {
+		backend.scheme = "pvc"
+		backend.host = nfs.PVCRef.Name
+	}
+	return backend, nil
+}
+
+// nfsMountPath is where the worker Job mounts the export.
+func nfsMountPath() string {
+	if mountPath := os.Getenv("NFS_MOUNT_PATH"); mountPath != "" {
+		return mountPath
+	}
+	return "/data"
+}
+
+func (b *nfsBackend) Location(key string) string {
+	return fmt.Sprintf("%s://%s%s", b.scheme, b.host, path.Join(b.path, key))
+}
+
+func (b *nfsBackend) Key(location string) (string, error) {
+	trimmed, ok := strings.CutPrefix(location, b.scheme+"://")
+	_, fullPath, found := strings.Cut(trimmed, "/")
+	if !ok || !found {
+		return "", fmt.Errorf("invalid %s location %q", b.scheme, location)
+	}
+	key, ok := strings.CutPrefix(path.Clean("/"+fullPath), strings.TrimRight(b.path, "/")+"/")
+	if !ok {
+		return "", fmt.Errorf("%s location %q is not under path %s", b.scheme, location, b.path)
+	}
+	return key, nil
+}
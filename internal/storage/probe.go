@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+)
+
+// probePrefix holds the sentinel objects written by Probe, outside of the per-cluster artifact tree.
+const probePrefix = ".backup-operator/probe/"
+
+// checker is implemented by backends that can confirm their bucket or container exists before Probe writes to it,
+// which gives a clearer error than a failed upload.
+type checker interface {
+	Check(ctx context.Context) error
+}
+
+// errProbeListed stops the listing in probeReadOnly once it has seen an object.
+var errProbeListed = errors.New("probe listed an object")
+
+// ReadOnly reports whether the location is mounted read-only, so nothing can be written to it.
+func ReadOnly(location *backupv1alpha1.BackupStorageLocation) bool {
+	return location.Spec.NFS != nil && location.Spec.NFS.ReadOnly
+}
+
+// Probe writes a sentinel object, reads it back and deletes it, and returns how long that took. Read-only
+// locations are only listed, and the first object found is described.
+func Probe(ctx context.Context, location *backupv1alpha1.BackupStorageLocation, backend Backend) (time.Duration, error) {
+	start := time.Now()
+	if c, ok := backend.(checker); ok {
+		if err := c.Check(ctx); err != nil {
+			return 0, err
+		}
+	}
+	if ReadOnly(location) {
+		if err := probeReadOnly(ctx, backend); err != nil {
+			return 0, err
+		}
+		return time.Since(start), nil
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, err
+	}
+	key := probePrefix + hex.EncodeToString(nonce)
+	if _, err := backend.Put(ctx, key, func(w io.Writer) error {
+		_, err := w.Write(nonce)
+		return err
+	}); err != nil {
+		return 0, fmt.Errorf("write sentinel object: %w", err)
+	}
+
+	reader, err := backend.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("read sentinel object: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, int64(len(nonce))+1))
+	_ = reader.Close()
+	if err != nil {
+		return 0, fmt.Errorf("read sentinel object: %w", err)
+	}
+	if !bytes.Equal(data, nonce) {
+		return 0, fmt.Errorf("sentinel object read back %d bytes that differ from what was written", len(data))
+	}
+
+	if err := backend.Delete(ctx, key); err != nil {
+		return 0, fmt.Errorf("delete sentinel object: %w", err)
+	}
+	return time.Since(start), nil
+}
+
+func probeReadOnly(ctx context.Context, backend Backend) error {
+	var first *Object
+	err := backend.List(ctx, "", func(object Object) error {
+		first = &object
+		return errProbeListed
+	})
+	if err != nil && !errors.Is(err, errProbeListed) {
+		return fmt.Errorf("list objects: %w", err)
+	}
+	if first == nil {
+		return nil
+	}
+	if _, err := backend.Stat(ctx, first.Key); err != nil {
+		return fmt.Errorf("describe object %s: %w", first.Key, err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// s3PartSize bounds how much of an object is held in memory during upload. S3 requires every part but the last
+// to be at least 5 MiB.
+const s3PartSize = 8 << 20
+
+type s3Config struct {
+	Endpoint        string
+	Bucket          string
+	Prefix          string
+	Region          string
+	ForcePathStyle  bool
+	InsecureSkipTLS bool
+	CABundle        []byte
+	AccessKey       string
+	SecretKey       string
+	SessionToken    string
+}
+
+type s3Backend struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func validateS3(location *backupv1alpha1.BackupStorageLocation) error {
+	if location.Spec.S3 == nil || location.Spec.S3.Bucket == "" {
+		return fmt.Errorf("s3 storage location requires bucket")
+	}
+	return nil
+}
+
+func newS3Backend(ctx context.Context, c client.Reader, location *backupv1alpha1.BackupStorageLocation) (Backend, error) {
+	cfg, err := loadS3Config(ctx, c, location)
+	if err != nil {
+		return nil, err
+	}
+	s3Client, err := buildS3Client(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Backend{client: s3Client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func loadS3Config(ctx context.Context, c client.Reader, location *backupv1alpha1.BackupStorageLocation) (*s3Config, error) {
+	cfg := &s3Config{
+		Endpoint:        location.Spec.S3.Endpoint,
+		Bucket:          location.Spec.S3.Bucket,
+		Prefix:          strings.Trim(location.Spec.S3.Prefix, "/"),
+		Region:          location.Spec.S3.Region,
+		ForcePathStyle:  location.Spec.S3.ForcePathStyle,
+		InsecureSkipTLS: location.Spec.S3.InsecureSkipTLS,
+		CABundle:        location.Spec.S3.CABundle,
+	}
+
+	if location.Spec.S3.SecretRef.Name != "" {
+		secret, err := readSecret(ctx, c, location.Spec.S3.SecretRef)
+		if err != nil {
+			return nil, err
+		}
+		cfg.AccessKey = string(secret.Data["accessKey"])
+		cfg.SecretKey = string(secret.Data["secretKey"])
+		cfg.SessionToken = string(secret.Data["sessionToken"])
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	return cfg, nil
+}
+
+func buildS3Client(ctx context.Context, cfg *s3Config) (*s3.Client, error) {
+	customTransport := &http.Transport{}
+	if cfg.InsecureSkipTLS || len(cfg.CABundle) > 0 {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipTLS}
+		if len(cfg.CABundle) > 0 {
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(cfg.CABundle)
+			tlsConfig.RootCAs = pool
+		}
+		customTransport.TLSClientConfig = tlsConfig
+	}
+
+	customHTTP := &http.Client{Transport: customTransport}
+
+	resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...any) (aws.Endpoint, error) {
+		if service == s3.ServiceID && cfg.Endpoint != "" {
+			return aws.Endpoint{URL: cfg.Endpoint, SigningRegion: cfg.Region, HostnameImmutable: true}, nil
+		}
+		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+	})
+
+	loadOptions := []func(*config.LoadOptions) error{
+		config.WithRegion(cfg.Region),
+		config.WithEndpointResolverWithOptions(resolver),
+		config.WithHTTPClient(customHTTP),
+	}
+	if cfg.AccessKey != "" && cfg.SecretKey != "" {
+		credProvider := credentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, cfg.SessionToken)
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(credProvider))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = cfg.ForcePathStyle
+	}), nil
+}
+
+func (b *s3Backend) objectKey(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+	return b.prefix + "/" + key
+}
+
+// Put streams write's output into a multipart upload.
+func (b *s3Backend) Put(ctx context.Context, key string, write func(io.Writer) error) (int64, error) {
+	objectKey := b.objectKey(key)
+	upload, err := b.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: &b.bucket,
+		Key:    &objectKey,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	parts := &s3PartWriter{ctx: ctx, client: b.client, bucket: b.bucket, key: objectKey, uploadID: upload.UploadId,
+		buf: make([]byte, 0, s3PartSize)}
+	err = write(parts)
+	if err == nil {
+		err = parts.flush()
+	}
+	if err == nil {
+		_, err = b.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:          &b.bucket,
+			Key:             &objectKey,
+			UploadId:        upload.UploadId,
+			MultipartUpload: &s3types.CompletedMultipartUpload{Parts: parts.completed},
+		})
+	}
+	if err != nil {
+		// Abort even when ctx is already cancelled so the uploaded parts do not linger in the bucket.
+		_, _ = b.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+			Bucket:   &b.bucket,
+			Key:      &objectKey,
+			UploadId: upload.UploadId,
+		})
+		return 0, err
+	}
+	return parts.size, nil
+}
+
+// s3PartWriter buffers one part at a time and uploads it as soon as it is full.
+type s3PartWriter struct {
+	ctx       context.Context
+	client    *s3.Client
+	bucket    string
+	key       string
+	uploadID  *string
+	buf       []byte
+	completed []s3types.CompletedPart
+	size      int64
+}
+
+func (w *s3PartWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		w.size += int64(n)
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush uploads the buffered bytes as the next part. An upload always gets at least one part.
+func (w *s3PartWriter) flush() error {
+	if len(w.buf) == 0 && len(w.completed) > 0 {
+		return nil
+	}
+	number := int32(len(w.completed) + 1)
+	resp, err := w.client.UploadPart(w.ctx, &s3.UploadPartInput{
+		Bucket:     &w.bucket,
+		Key:        &w.key,
+		UploadId:   w.uploadID,
+		PartNumber: aws.Int32(number),
+		Body:       bytes.NewReader(w.buf),
+	})
+	if err != nil {
+		return fmt.Errorf("upload part %d: %w", number, err)
+	}
+	w.completed = append(w.completed, s3types.CompletedPart{ETag: resp.ETag, PartNumber: aws.Int32(number)})
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (b *s3Backend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	objectKey := b.objectKey(key)
+	resp, err := b.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &b.bucket,
+		Key:    &objectKey,
+	})
+	if err != nil {
+		return nil, s3Error(key, err)
+	}
+	return resp.Body, nil
+}
+
+func (b *s3Backend) Stat(ctx context.Context, key string) (*Object, error) {
+	objectKey := b.objectKey(key)
+	resp, err := b.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &b.bucket,
+		Key:    &objectKey,
+	})
+	if err != nil {
+		return nil, s3Error(key, err)
+	}
+	return &Object{Key: key, Size: aws.ToInt64(resp.ContentLength), ModTime: aws.ToTime(resp.LastModified)}, nil
+}
+
+func (b *s3Backend) List(ctx context.Context, prefix string, visit func(Object) error) error {
+	objectPrefix := b.objectKey(prefix)
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: &b.bucket,
+		Prefix: &objectPrefix,
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range page.Contents {
+			key := aws.ToString(item.Key)
+			if b.prefix != "" {
+				key = strings.TrimPrefix(key, b.prefix+"/")
+			}
+			if err := visit(Object{Key: key, Size: aws.ToInt64(item.Size), ModTime: aws.ToTime(item.LastModified)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *s3Backend) Delete(ctx context.Context, key string) error {
+	objectKey := b.objectKey(key)
+	_, err := b.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &b.bucket,
+		Key:    &objectKey,
+	})
+	return err
+}
+
+func (b *s3Backend) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", b.bucket, b.objectKey(key))
+}
+
+func (b *s3Backend) Key(location string) (string, error) {
+	trimmed, ok := strings.CutPrefix(location, "s3://")
+	bucket, objectKey, found := strings.Cut(trimmed, "/")
+	if !ok || !found {
+		return "", fmt.Errorf("invalid s3 location %q", location)
+	}
+	if bucket != b.bucket {
+		return "", fmt.Errorf("s3 location %q is not in bucket %s", location, b.bucket)
+	}
+	if b.prefix == "" {
+		return objectKey, nil
+	}
+	key, ok := strings.CutPrefix(objectKey, b.prefix+"/")
+	if !ok {
+		return "", fmt.Errorf("s3 location %q is not under prefix %s", location, b.prefix)
+	}
+	return key, nil
+}
+
+// Check fails unless the bucket exists and the credentials can access it.
+func (b *s3Backend) Check(ctx context.Context) error {
+	if _, err := b.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &b.bucket}); err != nil {
+		return fmt.Errorf("head bucket %s: %w", b.bucket, err)
+	}
+	return nil
+}
+
+// s3Error maps the SDK's not-found errors to ErrNotFound.
+func s3Error(key string, err error) error {
+	var noSuchKey *s3types.NoSuchKey
+	var notFound *s3types.NotFound
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) {
+		return fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return err
+}
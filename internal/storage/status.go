@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PatchStatus applies mutate to the latest copy of location and writes its status with a merge patch, leaving the
+// fields other writers own alone. The storage location controller, the catalog controller and the workers all
+// record their outcomes here. A merge patch replaces the conditions list as a whole, so the patch is guarded by
+// the resourceVersion and retried on conflict rather than dropping a condition set meanwhile. location is left
+// holding what was written.
+func PatchStatus(ctx context.Context, c client.Client, location *backupv1alpha1.BackupStorageLocation, mutate func(*backupv1alpha1.BackupStorageLocation)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, client.ObjectKeyFromObject(location), location); err != nil {
+			return err
+		}
+		original := location.DeepCopy()
+		mutate(location)
+		return c.Status().Patch(ctx, location, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// SetAvailability records the outcome of validating a location in its status: the phase, the Available condition
+// and, when err is nil, the probe latency. reason is only used for failures.
+func SetAvailability(location *backupv1alpha1.BackupStorageLocation, reason string, latency time.Duration, err error) {
+	now := metav1.Now()
+	status := &location.Status
+	status.LastValidated = &now
+	status.ObservedGeneration = location.Generation
+
+	condition := metav1.Condition{
+		Type:               backupv1alpha1.StorageLocationConditionAvailable,
+		ObservedGeneration: location.Generation,
+	}
+	if err != nil {
+		status.Phase = backupv1alpha1.StorageLocationUnavailable
+		status.Message = err.Error()
+		status.ValidationLatency = nil
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = reason
+	} else {
+		status.Phase = backupv1alpha1.StorageLocationAvailable
+		status.Message = fmt.Sprintf("%s storage location reachable and writable", location.Spec.Type)
+		if ReadOnly(location) {
+			status.Message = fmt.Sprintf("%s storage location reachable (read-only)", location.Spec.Type)
+		}
+		status.ValidationLatency = &metav1.Duration{Duration: latency.Round(time.Millisecond)}
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = backupv1alpha1.ConditionReasonSucceeded
+	}
+	condition.Message = status.Message
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
@@ -0,0 +1,124 @@
+// Package storage stores backup artifacts in the object stores and file systems a BackupStorageLocation points at.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrNotFound is returned, possibly wrapped, for keys that do not exist.
+var ErrNotFound = errors.New("object not found")
+
+// IsNotFound reports whether err means the requested object does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Object describes a stored object.
+type Object struct {
+	// Key is relative to the root of the storage location.
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Backend stores objects under slash-separated keys relative to the root of a storage location, such as an S3
+// prefix, an NFS export, an Azure container or a GCS bucket.
+type Backend interface {
+	// Put streams the output of write into key and returns the number of bytes stored. The object only becomes
+	// visible once write returns without error.
+	Put(ctx context.Context, key string, write func(io.Writer) error) (int64, error)
+	// Get streams an object back. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat describes an object without reading it.
+	Stat(ctx context.Context, key string) (*Object, error)
+	// List calls visit for every object whose key starts with prefix, in lexical key order.
+	List(ctx context.Context, prefix string, visit func(Object) error) error
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// Location returns the URL recorded in status for key, e.g. s3://bucket/prefix/key.
+	Location(key string) string
+	// Key maps a URL returned by Location back to its key.
+	Key(location string) (string, error)
+}
+
+// Driver implements one StorageLocationType.
+type Driver struct {
+	// Validate checks the location's settings without contacting the backend.
+	Validate func(location *backupv1alpha1.BackupStorageLocation) error
+	// New connects to the backend. c is used to read credential Secrets.
+	New func(ctx context.Context, c client.Reader, location *backupv1alpha1.BackupStorageLocation) (Backend, error)
+}
+
+var drivers = map[backupv1alpha1.StorageLocationType]Driver{
+	backupv1alpha1.StorageLocationS3:    {Validate: validateS3, New: newS3Backend},
+	backupv1alpha1.StorageLocationNFS:   {Validate: validateNFS, New: newNFSBackend},
+	backupv1alpha1.StorageLocationAzure: {Validate: validateAzure, New: newAzureBackend},
+	backupv1alpha1.StorageLocationGCS:   {Validate: validateGCS, New: newGCSBackend},
+}
+
+// Register adds or replaces the driver for a storage type. It is meant to be called during initialization, e.g. to
+// back a type with NewMemory in tests.
+func Register(storageType backupv1alpha1.StorageLocationType, driver Driver) {
+	drivers[storageType] = driver
+}
+
+// Types lists the storage types with a registered driver.
+func Types() []backupv1alpha1.StorageLocationType {
+	types := make([]backupv1alpha1.StorageLocationType, 0, len(drivers))
+	for storageType := range drivers {
+		types = append(types, storageType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Validate checks a storage location's settings for its type.
+func Validate(location *backupv1alpha1.BackupStorageLocation) error {
+	driver, ok := drivers[location.Spec.Type]
+	if !ok {
+		return fmt.Errorf("unsupported storage type %q", location.Spec.Type)
+	}
+	return driver.Validate(location)
+}
+
+// New returns the backend for a storage location.
+func New(ctx context.Context, c client.Reader, location *backupv1alpha1.BackupStorageLocation) (Backend, error) {
+	driver, ok := drivers[location.Spec.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported storage type %q", location.Spec.Type)
+	}
+	if err := driver.Validate(location); err != nil {
+		return nil, err
+	}
+	return driver.New(ctx, c, location)
+}
+
+// readSecret reads a credentials Secret. An empty namespace means the operator's namespace.
+func readSecret(ctx context.Context, c client.Reader, ref corev1.SecretReference) (*corev1.Secret, error) {
+	key := client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}
+	if key.Namespace == "" {
+		key.Namespace = operatorNamespace()
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func operatorNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	return "backup-operator-system"
+}
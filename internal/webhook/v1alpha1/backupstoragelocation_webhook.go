@@ -0,0 +1,111 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"example.com/backup-operator/internal/signing"
+	"example.com/backup-operator/internal/storage"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupBackupStorageLocationWebhookWithManager registers the validating webhook for BackupStorageLocation.
+func SetupBackupStorageLocationWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&backupv1alpha1.BackupStorageLocation{}).
+		WithValidator(&BackupStorageLocationCustomValidator{client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-backup-example-com-v1alpha1-backupstoragelocation,mutating=false,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=backupstoragelocations,verbs=create;update,versions=v1alpha1,name=vbackupstoragelocation-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// BackupStorageLocationCustomValidator rejects incomplete storage configuration and competing defaults.
+type BackupStorageLocationCustomValidator struct {
+	client client.Reader
+}
+
+var _ webhook.CustomValidator = &BackupStorageLocationCustomValidator{}
+
+func (v *BackupStorageLocationCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	location, ok := obj.(*backupv1alpha1.BackupStorageLocation)
+	if !ok {
+		return nil, fmt.Errorf("expected a BackupStorageLocation object but got %T", obj)
+	}
+	return nil, v.validate(ctx, location)
+}
+
+func (v *BackupStorageLocationCustomValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	location, ok := newObj.(*backupv1alpha1.BackupStorageLocation)
+	if !ok {
+		return nil, fmt.Errorf("expected a BackupStorageLocation object but got %T", newObj)
+	}
+	return nil, v.validate(ctx, location)
+}
+
+func (v *BackupStorageLocationCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *BackupStorageLocationCustomValidator) validate(ctx context.Context, location *backupv1alpha1.BackupStorageLocation) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if !slices.Contains(storage.Types(), location.Spec.Type) {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("type"), location.Spec.Type, storage.Types()))
+	} else if err := storage.Validate(location); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath, location.Spec.Type, err.Error()))
+	}
+	if interval := location.Spec.ValidationInterval; interval != nil && interval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("validationInterval"), interval.Duration.String(), "must not be negative"))
+	}
+	if interval := location.Spec.SyncInterval; interval != nil && interval.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("syncInterval"), interval.Duration.String(), "must not be negative"))
+	}
+	allErrs = append(allErrs, validateEncryption(location.Spec.Encryption, specPath.Child("encryption"))...)
+	allErrs = append(allErrs, validateSigning(location.Spec.Signing, specPath.Child("signing"))...)
+
+	if location.Spec.Default {
+		var list backupv1alpha1.BackupStorageLocationList
+		if err := v.client.List(ctx, &list); err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		for i := range list.Items {
+			other := &list.Items[i]
+			if other.Name != location.Name && other.Spec.Default {
+				allErrs = append(allErrs, field.Forbidden(specPath.Child("default"),
+					fmt.Sprintf("BackupStorageLocation %s is already the default", other.Name)))
+			}
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(backupv1alpha1.GroupVersion.WithKind("BackupStorageLocation").GroupKind(), location.Name, allErrs)
+}
+
+func validateSigning(spec *backupv1alpha1.SigningSpec, path *field.Path) field.ErrorList {
+	if spec == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	if spec.SecretRef == nil && len(spec.TrustedPublicKeys) == 0 {
+		allErrs = append(allErrs, field.Required(path, "secretRef or trustedPublicKeys is required"))
+	}
+	if spec.SecretRef != nil && spec.SecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("secretRef", "name"), ""))
+	}
+	for i, key := range spec.TrustedPublicKeys {
+		if _, err := signing.ParsePublicKey([]byte(key)); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("trustedPublicKeys").Index(i), "<public key>", err.Error()))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,104 @@
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupClusterBackupWebhookWithManager registers the defaulting and validating webhooks for ClusterBackup.
+func SetupClusterBackupWebhookWithManager(mgr ctrl.Manager) error {
+	catalog, err := newResourceCatalog(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr).For(&backupv1alpha1.ClusterBackup{}).
+		WithDefaulter(&ClusterBackupCustomDefaulter{}).
+		WithValidator(&ClusterBackupCustomValidator{resources: catalog}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-backup-example-com-v1alpha1-clusterbackup,mutating=true,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=clusterbackups,verbs=create;update,versions=v1alpha1,name=mclusterbackup-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ClusterBackupCustomDefaulter fills in ClusterBackup defaults.
+type ClusterBackupCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ClusterBackupCustomDefaulter{}
+
+func (d *ClusterBackupCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	backup, ok := obj.(*backupv1alpha1.ClusterBackup)
+	if !ok {
+		return fmt.Errorf("expected a ClusterBackup object but got %T", obj)
+	}
+	defaultBackupSpec(&backup.Spec.BackupSpec)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-backup-example-com-v1alpha1-clusterbackup,mutating=false,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=clusterbackups,verbs=create;update,versions=v1alpha1,name=vclusterbackup-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ClusterBackupCustomValidator rejects ClusterBackup specs the worker would fail on.
+type ClusterBackupCustomValidator struct {
+	resources *resourceCatalog
+}
+
+var _ webhook.CustomValidator = &ClusterBackupCustomValidator{}
+
+func (v *ClusterBackupCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	backup, ok := obj.(*backupv1alpha1.ClusterBackup)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterBackup object but got %T", obj)
+	}
+	if err := validateSyncedLabel(ctx, "clusterbackups", nil, backup); err != nil {
+		return nil, err
+	}
+	return nil, v.validate(ctx, backup)
+}
+
+func (v *ClusterBackupCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldBackup, ok := oldObj.(*backupv1alpha1.ClusterBackup)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterBackup object but got %T", oldObj)
+	}
+	backup, ok := newObj.(*backupv1alpha1.ClusterBackup)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterBackup object but got %T", newObj)
+	}
+	if err := validateSyncedLabel(ctx, "clusterbackups", oldBackup, backup); err != nil {
+		return nil, err
+	}
+	// Metadata-only updates (labels, finalizers) must not fail because the cluster changed since creation.
+	if equality.Semantic.DeepEqual(oldBackup.Spec, backup.Spec) {
+		return nil, nil
+	}
+	if hasSyncedLabel(oldBackup) {
+		return nil, apierrors.NewForbidden(backupv1alpha1.GroupVersion.WithResource("clusterbackups").GroupResource(), backup.Name,
+			errors.New("the spec of a backup synced from storage is read-only"))
+	}
+	return nil, v.validate(ctx, backup)
+}
+
+func (v *ClusterBackupCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ClusterBackupCustomValidator) validate(ctx context.Context, backup *backupv1alpha1.ClusterBackup) error {
+	// A synced backup records an artifact that already exists, possibly written by another cluster whose resources
+	// this one does not have.
+	if isSyncedBackup(ctx, backup) {
+		return nil
+	}
+	allErrs := validateBackupSpec(backup.Spec.BackupSpec, field.NewPath("spec"), v.resources)
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(backupv1alpha1.GroupVersion.WithKind("ClusterBackup").GroupKind(), backup.Name, allErrs)
+}
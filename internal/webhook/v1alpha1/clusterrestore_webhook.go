@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupClusterRestoreWebhookWithManager registers the defaulting and validating webhooks for ClusterRestore.
+func SetupClusterRestoreWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&backupv1alpha1.ClusterRestore{}).
+		WithDefaulter(&ClusterRestoreCustomDefaulter{}).
+		WithValidator(&ClusterRestoreCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-backup-example-com-v1alpha1-clusterrestore,mutating=true,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=clusterrestores,verbs=create;update,versions=v1alpha1,name=mclusterrestore-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ClusterRestoreCustomDefaulter fills in ClusterRestore defaults.
+type ClusterRestoreCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ClusterRestoreCustomDefaulter{}
+
+func (d *ClusterRestoreCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	restore, ok := obj.(*backupv1alpha1.ClusterRestore)
+	if !ok {
+		return fmt.Errorf("expected a ClusterRestore object but got %T", obj)
+	}
+	defaultRestoreSpec(&restore.Spec.RestoreSpec)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-backup-example-com-v1alpha1-clusterrestore,mutating=false,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=clusterrestores,verbs=create;update,versions=v1alpha1,name=vclusterrestore-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ClusterRestoreCustomValidator rejects ClusterRestore specs the worker would fail on.
+type ClusterRestoreCustomValidator struct{}
+
+var _ webhook.CustomValidator = &ClusterRestoreCustomValidator{}
+
+func (v *ClusterRestoreCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	restore, ok := obj.(*backupv1alpha1.ClusterRestore)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterRestore object but got %T", obj)
+	}
+	return nil, v.validate(restore)
+}
+
+func (v *ClusterRestoreCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	restore, ok := newObj.(*backupv1alpha1.ClusterRestore)
+	if !ok {
+		return nil, fmt.Errorf("expected a ClusterRestore object but got %T", newObj)
+	}
+	return nil, v.validate(restore)
+}
+
+func (v *ClusterRestoreCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ClusterRestoreCustomValidator) validate(restore *backupv1alpha1.ClusterRestore) error {
+	allErrs := validateRestoreSpec(restore.Spec.RestoreSpec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(backupv1alpha1.GroupVersion.WithKind("ClusterRestore").GroupKind(), restore.Name, allErrs)
+}
@@ -0,0 +1,97 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupRemoteClusterWebhookWithManager registers the defaulting and validating webhooks for RemoteCluster.
+func SetupRemoteClusterWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&backupv1alpha1.RemoteCluster{}).
+		WithDefaulter(&RemoteClusterCustomDefaulter{}).
+		WithValidator(&RemoteClusterCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-backup-example-com-v1alpha1-remotecluster,mutating=true,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=remoteclusters,verbs=create;update,versions=v1alpha1,name=mremotecluster-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RemoteClusterCustomDefaulter fills in RemoteCluster defaults.
+type RemoteClusterCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &RemoteClusterCustomDefaulter{}
+
+func (d *RemoteClusterCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	remote, ok := obj.(*backupv1alpha1.RemoteCluster)
+	if !ok {
+		return fmt.Errorf("expected a RemoteCluster object but got %T", obj)
+	}
+	if remote.Spec.Auth.Method == "" {
+		remote.Spec.Auth.Method = backupv1alpha1.RemoteAuthServiceAccountToken
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-backup-example-com-v1alpha1-remotecluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=remoteclusters,verbs=create;update,versions=v1alpha1,name=vremotecluster-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RemoteClusterCustomValidator rejects RemoteCluster specs that cannot produce a client.
+type RemoteClusterCustomValidator struct{}
+
+var _ webhook.CustomValidator = &RemoteClusterCustomValidator{}
+
+func (v *RemoteClusterCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	remote, ok := obj.(*backupv1alpha1.RemoteCluster)
+	if !ok {
+		return nil, fmt.Errorf("expected a RemoteCluster object but got %T", obj)
+	}
+	return nil, v.validate(remote)
+}
+
+func (v *RemoteClusterCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	remote, ok := newObj.(*backupv1alpha1.RemoteCluster)
+	if !ok {
+		return nil, fmt.Errorf("expected a RemoteCluster object but got %T", newObj)
+	}
+	return nil, v.validate(remote)
+}
+
+func (v *RemoteClusterCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RemoteClusterCustomValidator) validate(remote *backupv1alpha1.RemoteCluster) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	// Kubeconfig auth takes the server from the kubeconfig, so apiServer is only required for token auth.
+	usesToken := remote.Spec.Auth.Method == "" || remote.Spec.Auth.Method == backupv1alpha1.RemoteAuthServiceAccountToken
+	if remote.Spec.APIServer == "" {
+		if usesToken {
+			allErrs = append(allErrs, field.Required(specPath.Child("apiServer"), ""))
+		}
+	} else if _, _, err := rest.DefaultServerUrlFor(&rest.Config{Host: remote.Spec.APIServer}); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("apiServer"), remote.Spec.APIServer, err.Error()))
+	}
+
+	authPath := specPath.Child("auth")
+	if remote.Spec.Auth.Method != "" {
+		allErrs = append(allErrs, validateEnum(authPath.Child("method"), string(remote.Spec.Auth.Method),
+			string(backupv1alpha1.RemoteAuthServiceAccountToken), string(backupv1alpha1.RemoteAuthKubeconfig))...)
+	}
+	if remote.Spec.Auth.SecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(authPath.Child("secretRef", "name"), ""))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(backupv1alpha1.GroupVersion.WithKind("RemoteCluster").GroupKind(), remote.Name, allErrs)
+}
@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupRestoreWebhookWithManager registers the defaulting and validating webhooks for Restore.
+func SetupRestoreWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&backupv1alpha1.Restore{}).
+		WithDefaulter(&RestoreCustomDefaulter{}).
+		WithValidator(&RestoreCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-backup-example-com-v1alpha1-restore,mutating=true,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=restores,verbs=create;update,versions=v1alpha1,name=mrestore-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RestoreCustomDefaulter fills in Restore defaults.
+type RestoreCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &RestoreCustomDefaulter{}
+
+func (d *RestoreCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	restore, ok := obj.(*backupv1alpha1.Restore)
+	if !ok {
+		return fmt.Errorf("expected a Restore object but got %T", obj)
+	}
+	defaultRestoreSpec(&restore.Spec)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-backup-example-com-v1alpha1-restore,mutating=false,failurePolicy=fail,sideEffects=None,groups=backup.example.com,resources=restores,verbs=create;update,versions=v1alpha1,name=vrestore-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RestoreCustomValidator rejects Restore specs the worker would fail on.
+type RestoreCustomValidator struct{}
+
+var _ webhook.CustomValidator = &RestoreCustomValidator{}
+
+func (v *RestoreCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	restore, ok := obj.(*backupv1alpha1.Restore)
+	if !ok {
+		return nil, fmt.Errorf("expected a Restore object but got %T", obj)
+	}
+	return nil, v.validate(restore)
+}
+
+func (v *RestoreCustomValidator) ValidateUpdate(_ context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	restore, ok := newObj.(*backupv1alpha1.Restore)
+	if !ok {
+		return nil, fmt.Errorf("expected a Restore object but got %T", newObj)
+	}
+	return nil, v.validate(restore)
+}
+
+func (v *RestoreCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RestoreCustomValidator) validate(restore *backupv1alpha1.Restore) error {
+	allErrs := validateRestoreSpec(restore.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(backupv1alpha1.GroupVersion.WithKind("Restore").GroupKind(), restore.Name, allErrs)
+}
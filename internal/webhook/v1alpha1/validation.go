@@ -0,0 +1,297 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	backupv1alpha1 "example.com/backup-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func defaultBackupSpec(spec *backupv1alpha1.BackupSpec) {
+	if spec.Export == nil {
+		spec.Export = &backupv1alpha1.ExportSpec{}
+	}
+	if spec.Export.Format == "" {
+		spec.Export.Format = backupv1alpha1.ExportFormatYAML
+	}
+	if spec.ExecutionMode == "" {
+		spec.ExecutionMode = backupv1alpha1.ExecutionModeAsync
+	}
+	if spec.DeletionPolicy == "" {
+		spec.DeletionPolicy = backupv1alpha1.BackupDeletionPolicyDelete
+	}
+	if spec.ErrorPolicy == "" {
+		spec.ErrorPolicy = backupv1alpha1.BackupErrorPolicyContinue
+	}
+}
+
+func defaultRestoreSpec(spec *backupv1alpha1.RestoreSpec) {
+	if spec.OverwritePolicy == "" {
+		spec.OverwritePolicy = backupv1alpha1.RestoreOverwriteMerge
+	}
+	if spec.ExecutionMode == "" {
+		spec.ExecutionMode = backupv1alpha1.ExecutionModeAsync
+	}
+	if spec.ErrorPolicy == "" {
+		spec.ErrorPolicy = backupv1alpha1.RestoreErrorPolicyFailFast
+	}
+}
+
+func validateBackupSpec(spec backupv1alpha1.BackupSpec, path *field.Path, catalog *resourceCatalog) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.Export != nil && spec.Export.Format != "" {
+		allErrs = append(allErrs, validateEnum(path.Child("export", "format"), string(spec.Export.Format),
+			string(backupv1alpha1.ExportFormatYAML), string(backupv1alpha1.ExportFormatJSON))...)
+	}
+	if spec.ExecutionMode != "" {
+		allErrs = append(allErrs, validateEnum(path.Child("executionMode"), string(spec.ExecutionMode),
+			string(backupv1alpha1.ExecutionModeAsync), string(backupv1alpha1.ExecutionModeSync))...)
+	}
+	if spec.DeletionPolicy != "" {
+		allErrs = append(allErrs, validateEnum(path.Child("deletionPolicy"), string(spec.DeletionPolicy),
+			string(backupv1alpha1.BackupDeletionPolicyDelete), string(backupv1alpha1.BackupDeletionPolicyRetain))...)
+	}
+	if spec.ErrorPolicy != "" {
+		allErrs = append(allErrs, validateEnum(path.Child("errorPolicy"), string(spec.ErrorPolicy),
+			string(backupv1alpha1.BackupErrorPolicyContinue), string(backupv1alpha1.BackupErrorPolicyFail))...)
+	}
+
+	if spec.Snapshot != nil && spec.Snapshot.PVCSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.Snapshot.PVCSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("snapshot", "pvcSelector"), spec.Snapshot.PVCSelector, err.Error()))
+		}
+	}
+
+	if spec.Resources != nil {
+		resourcesPath := path.Child("resources")
+		if spec.Resources.LabelSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(spec.Resources.LabelSelector); err != nil {
+				allErrs = append(allErrs, field.Invalid(resourcesPath.Child("labelSelector"), spec.Resources.LabelSelector, err.Error()))
+			}
+		}
+		if len(spec.Resources.IncludedResources) > 0 {
+			unknown, err := catalog.unknown(spec.Resources.IncludedResources)
+			if err != nil {
+				allErrs = append(allErrs, field.InternalError(resourcesPath.Child("includedResources"), err))
+			}
+			for i, name := range spec.Resources.IncludedResources {
+				if unknown.Has(name) {
+					allErrs = append(allErrs, field.Invalid(resourcesPath.Child("includedResources").Index(i), name,
+						"resource is not served by the API server"))
+				}
+			}
+		}
+	}
+
+	if spec.TTL != nil && spec.RetainUntil != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("retainUntil"), spec.RetainUntil, "may not be combined with ttl"))
+	}
+	if spec.TTL != nil && spec.TTL.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("ttl"), spec.TTL.Duration.String(), "must be positive"))
+	}
+	allErrs = append(allErrs, validateEncryption(spec.Encryption, path.Child("encryption"))...)
+
+	return allErrs
+}
+
+func validateEncryption(enc *backupv1alpha1.EncryptionSpec, path *field.Path) field.ErrorList {
+	if enc == nil || enc.SecretRef.Name != "" {
+		return nil
+	}
+	return field.ErrorList{field.Required(path.Child("secretRef", "name"), "")}
+}
+
+func validateRestoreSpec(spec backupv1alpha1.RestoreSpec, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	sourcePath := path.Child("sourceRef")
+	switch spec.SourceRef.Kind {
+	case "Backup":
+		if spec.SourceRef.Namespace == "" {
+			allErrs = append(allErrs, field.Required(sourcePath.Child("namespace"), "namespace is required for Backup"))
+		}
+	case "ClusterBackup":
+	case backupv1alpha1.RestoreSourceArtifact:
+		allErrs = append(allErrs, validateArtifactSource(spec.SourceRef, sourcePath)...)
+	default:
+		allErrs = append(allErrs, field.NotSupported(sourcePath.Child("kind"), spec.SourceRef.Kind,
+			[]string{"Backup", "ClusterBackup", backupv1alpha1.RestoreSourceArtifact}))
+	}
+	if spec.SourceRef.Kind != backupv1alpha1.RestoreSourceArtifact {
+		if spec.SourceRef.Name == "" {
+			allErrs = append(allErrs, field.Required(sourcePath.Child("name"), ""))
+		}
+		if spec.SourceRef.Artifact != nil {
+			allErrs = append(allErrs, field.Forbidden(sourcePath.Child("artifact"), "only allowed for kind Artifact"))
+		}
+	}
+
+	if spec.OverwritePolicy != "" {
+		allErrs = append(allErrs, validateEnum(path.Child("overwritePolicy"), string(spec.OverwritePolicy),
+			string(backupv1alpha1.RestoreOverwriteMerge), string(backupv1alpha1.RestoreOverwriteReplace),
+			string(backupv1alpha1.RestoreOverwriteSkip))...)
+	}
+	if spec.ExecutionMode != "" {
+		allErrs = append(allErrs, validateEnum(path.Child("executionMode"), string(spec.ExecutionMode),
+			string(backupv1alpha1.ExecutionModeAsync), string(backupv1alpha1.ExecutionModeSync))...)
+	}
+	if spec.ErrorPolicy != "" {
+		allErrs = append(allErrs, validateEnum(path.Child("errorPolicy"), string(spec.ErrorPolicy),
+			string(backupv1alpha1.RestoreErrorPolicyFailFast), string(backupv1alpha1.RestoreErrorPolicyContinue))...)
+	}
+	if spec.ResourceModifierRef != nil && spec.ResourceModifierRef.Name == "" {
+		allErrs = append(allErrs, field.Required(path.Child("resourceModifierRef", "name"), ""))
+	}
+
+	return allErrs
+}
+
+// hasSyncedLabel reports whether a backup resource carries the label catalog sync sets on the resources it
+// recreates from artifacts in storage. validateSyncedLabel keeps anyone else from setting it, so it can be trusted on
+// objects that were already admitted.
+func hasSyncedLabel(obj metav1.Object) bool {
+	return obj.GetLabels()[backupv1alpha1.BackupSyncedLabel] == "true"
+}
+
+// isSyncedBackup reports whether the request creates or updates a backup resource recreated by catalog sync. The
+// label is only trusted on requests from the operator's service account, which the catalog worker runs as.
+func isSyncedBackup(ctx context.Context, obj metav1.Object) bool {
+	return hasSyncedLabel(obj) && requestedByOperator(ctx)
+}
+
+// validateSyncedLabel rejects requests from anyone but the operator that add the synced label, which would skip
+// validation and keep the backup from ever running. oldObj is nil on create.
+func validateSyncedLabel(ctx context.Context, resource string, oldObj, obj metav1.Object) error {
+	if !hasSyncedLabel(obj) || (oldObj != nil && hasSyncedLabel(oldObj)) || requestedByOperator(ctx) {
+		return nil
+	}
+	return apierrors.NewForbidden(backupv1alpha1.GroupVersion.WithResource(resource).GroupResource(), obj.GetName(),
+		fmt.Errorf("only the operator may set the %s label", backupv1alpha1.BackupSyncedLabel))
+}
+
+func requestedByOperator(ctx context.Context) bool {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return false
+	}
+	return req.UserInfo.Username == "system:serviceaccount:"+operatorNamespace()+":"+operatorServiceAccount()
+}
+
+func operatorNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	return "backup-operator-system"
+}
+
+func operatorServiceAccount() string {
+	if sa := os.Getenv("POD_SERVICE_ACCOUNT"); sa != "" {
+		return sa
+	}
+	return "controller-manager"
+}
+
+// validateArtifactSource requires either the artifact path or the name of the backup that wrote it, not both.
+func validateArtifactSource(ref backupv1alpha1.RestoreSourceRef, path *field.Path) field.ErrorList {
+	artifactPath := path.Child("artifact")
+	switch {
+	case ref.Artifact == nil:
+		return field.ErrorList{field.Required(artifactPath, "artifact is required for kind Artifact")}
+	case ref.Artifact.Path == "":
+		if ref.Name == "" {
+			return field.ErrorList{field.Required(path.Child("name"), "name or artifact.path is required for kind Artifact")}
+		}
+	case ref.Name != "" || ref.Namespace != "" || ref.Artifact.ClusterID != "" || ref.Artifact.Timestamp != "":
+		return field.ErrorList{field.Invalid(artifactPath.Child("path"), ref.Artifact.Path,
+			"cannot be combined with name, namespace, artifact.clusterID or artifact.timestamp")}
+	}
+	return nil
+}
+
+func validateEnum(path *field.Path, value string, supported ...string) field.ErrorList {
+	for _, candidate := range supported {
+		if value == candidate {
+			return nil
+		}
+	}
+	return field.ErrorList{field.NotSupported(path, value, supported)}
+}
+
+// resourceCatalog answers whether resource names used in selectors are served by the API server.
+// Names are matched the same way the backup worker matches them: resource, kind, resource.group or kind.group.
+type resourceCatalog struct {
+	discovery discovery.CachedDiscoveryInterface
+}
+
+func newResourceCatalog(cfg *rest.Config) (*resourceCatalog, error) {
+	disco, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &resourceCatalog{discovery: memory.NewMemCacheClient(disco)}, nil
+}
+
+// unknown returns the names that do not match any served resource. The cache is refreshed once before
+// giving up so recently installed CRDs are found.
+func (c *resourceCatalog) unknown(names []string) (sets.Set[string], error) {
+	var unknown sets.Set[string]
+	for attempt := 0; attempt < 2; attempt++ {
+		if attempt > 0 {
+			c.discovery.Invalidate()
+		}
+		known, err := c.names()
+		if err != nil {
+			return nil, err
+		}
+		unknown = sets.New[string]()
+		for _, name := range names {
+			if !known.Has(strings.ToLower(strings.TrimSpace(name))) {
+				unknown.Insert(name)
+			}
+		}
+		if unknown.Len() == 0 {
+			break
+		}
+	}
+	return unknown, nil
+}
+
+func (c *resourceCatalog) names() (sets.Set[string], error) {
+	lists, err := c.discovery.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	known := sets.New[string]()
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, res := range list.APIResources {
+			if strings.Contains(res.Name, "/") {
+				continue
+			}
+			name := strings.ToLower(res.Name)
+			kind := strings.ToLower(res.Kind)
+			known.Insert(name, kind)
+			if gv.Group != "" {
+				group := strings.ToLower(gv.Group)
+				known.Insert(fmt.Sprintf("%s.%s", name, group), fmt.Sprintf("%s.%s", kind, group))
+			}
+		}
+	}
+	return known, nil
+}